@@ -0,0 +1,143 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+//AddForce adds to the force pushing the body through its centre of mass.
+// Forces are applied over the next frame's physics steps, then cleared.
+// Frames without a step keep them until a frame that has one
+func (b *PhysicsBody) AddForce(force [3]float32) {
+	b.force = add(b.force, force)
+	b.wake()
+}
+
+//AddTorque adds to the torque turning the body, it's applied over the next
+// physics steps and cleared the same as forces
+func (b *PhysicsBody) AddTorque(torque [3]float32) {
+	b.torque = add(b.torque, torque)
+	b.wake()
+}
+
+func (b *PhysicsBody) clearForces() {
+	b.force = [3]float32{}
+	b.torque = [3]float32{}
+}
+
+//ApplyImpulse instantly changes the body's momentum by the impulse, applied
+// at the point in world space, so off centre impulses spin the body
+func (b *PhysicsBody) ApplyImpulse(impulse, point [3]float32) {
+	mass := b.Mass()
+	if mass <= 0 {
+		return
+	}
+	//newton takes the change in velocity at the point
+	deltaVelocity := scale(impulse, 1/mass)
+	b.Body.AddImpulse(&deltaVelocity, &point)
+	b.wake()
+}
+
+//Mass returns 0 for kinematic bodies
+func (b *PhysicsBody) Mass() float32 {
+	if b.kinematic {
+		return 0
+	}
+	return b.mass
+}
+
+func (b *PhysicsBody) Velocity() [3]float32 {
+	var velocity [3]float32
+	b.Body.Velocity(&velocity)
+	return velocity
+}
+
+func (b *PhysicsBody) SetVelocity(velocity [3]float32) {
+	b.Body.SetVelocity(&velocity)
+	b.wake()
+}
+
+//Omega is the body's angular velocity in radians per second
+func (b *PhysicsBody) Omega() [3]float32 {
+	var omega [3]float32
+	b.Body.Omega(&omega)
+	return omega
+}
+
+func (b *PhysicsBody) SetOmega(omega [3]float32) {
+	b.Body.SetOmega(&omega)
+	b.wake()
+}
+
+func (b *PhysicsBody) GravityScale() float32 { return b.gravityScale }
+
+//SetGravityScale scales how strongly gravity pulls the body, 0 floats
+func (b *PhysicsBody) SetGravityScale(scale float32) {
+	b.gravityScale = scale
+	b.wake()
+}
+
+//SetDamping sets how quickly the body slows down moving and turning,
+// from 0 to 1
+func (b *PhysicsBody) SetDamping(linear, angular float32) {
+	b.Body.SetLinearDamping(linear)
+	b.Body.SetAngularDamping(&[3]float32{angular, angular, angular})
+}
+
+//Damping returns the linear and angular damping
+func (b *PhysicsBody) Damping() (linear, angular float32) {
+	var angularDamping [3]float32
+	b.Body.AngularDamping(&angularDamping)
+	return b.Body.LinearDamping(), angularDamping[0]
+}
+
+//Frozen bodies don't move until unfrozen, forces and impulses don't wake them
+func (b *PhysicsBody) Frozen() bool { return b.frozen }
+
+func (b *PhysicsBody) SetFrozen(frozen bool) {
+	b.frozen = frozen
+	if frozen {
+		b.Body.SetFreezeState(1)
+	} else {
+		b.Body.SetFreezeState(0)
+	}
+}
+
+//Sleeping bodies have come to rest and aren't simulated until something
+// touches or pushes them
+func (b *PhysicsBody) Sleeping() bool { return b.Body.SleepState() != 0 }
+
+//SetAutoSleep sets whether the body goes to sleep when it comes to rest
+func (b *PhysicsBody) SetAutoSleep(autoSleep bool) {
+	if autoSleep {
+		b.Body.SetAutoSleep(1)
+	} else {
+		b.Body.SetAutoSleep(0)
+	}
+}
+
+//wake lets a sleeping body respond to what was just done to it
+func (b *PhysicsBody) wake() {
+	if !b.frozen && b.Sleeping() {
+		b.Body.SetFreezeState(0)
+	}
+}
+
+func (b *PhysicsBody) Kinematic() bool { return b.kinematic }
+
+//SetKinematic makes the body ignore forces and gravity, and follow its node
+// instead, while still pushing dynamic bodies out of its way
+func (b *PhysicsBody) SetKinematic(kinematic bool) {
+	if kinematic == b.kinematic {
+		return
+	}
+	b.kinematic = kinematic
+	if kinematic {
+		b.Body.SetMassMatrix(0, 0, 0, 0)
+		b.Body.Matrix(&b.lastMatrix)
+		b.Body.SetVelocity(&[3]float32{})
+		b.Body.SetOmega(&[3]float32{})
+		return
+	}
+	b.Body.SetMassMatrix(b.mass, b.inertia[0], b.inertia[1], b.inertia[2])
+	b.wake()
+}
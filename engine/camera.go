@@ -0,0 +1,554 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gohorde/horde3d"
+	"bitbucket.org/tshannon/vmath"
+	"github.com/jteeuwen/glfw"
+	"math"
+)
+
+//Cameras are registered with the camera manager as views.  The highest
+// priority enabled view without a viewport or output texture is the main
+// view, and the render camera follows it, blending between views when the
+// main view changes.  Views with a viewport are drawn over the main view
+// (split screen, picture in picture) and views with an output texture are
+// drawn to that texture (in world monitors) each frame.
+
+//Viewport is the area of the window a view is drawn to, from 0 to 1 with
+// 0,0 at the top left of the window
+type Viewport struct {
+	X, Y, Width, Height float32
+}
+
+//CameraView is a camera registered with the camera manager
+type CameraView struct {
+	name     string
+	camera   *Camera
+	priority int
+	enabled  bool
+	order    int     //when the view was enabled, newest wins priority ties
+	fov      float32 //0 uses the FOV setting
+	viewport *Viewport
+	output   *Texture
+	outW     int
+	outH     int
+}
+
+//cameraState is what gets blended between views
+type cameraState struct {
+	translate [3]float32
+	rotation  quaternion
+	fov       float32
+	nearPlane float32
+	farPlane  float32
+}
+
+var (
+	renderCamera  *Camera
+	cameraViews   = make(map[string]*CameraView)
+	mainView      *CameraView
+	enableCount   int
+	defaultFOV    float32
+	viewState     cameraState //state the render camera was last set to
+	blendFrom     cameraState
+	blendProgress float32 = 1
+	blendTween    *Tween
+	tempCamMatrix = &vmath.Matrix4{}
+	//fov, near and far plane the render camera's projection was set up with
+	appliedProjection [3]float32
+)
+
+const (
+	defaultNearPlane = 0.1
+	defaultFarPlane  = 1000
+)
+
+//initCameras creates the render camera that the main view is drawn through
+func initCameras(fov float32) error {
+	defaultFOV = fov
+	return resetCameras()
+}
+
+//resetCameras removes all views and recreates the render camera
+func resetCameras() error {
+	for name := range cameraViews {
+		delete(cameraViews, name)
+	}
+	mainView = nil
+	stopCameraBlend()
+
+	pipeline, err := loadDefaultPipeline()
+	if err != nil {
+		return err
+	}
+	renderCamera = AddCamera(Root, "RenderCamera", pipeline)
+
+	viewState = cameraState{
+		rotation:  identityQuaternion,
+		fov:       defaultFOV,
+		nearPlane: defaultNearPlane,
+		farPlane:  defaultFarPlane,
+	}
+	applyCameraState(&viewState)
+	resetView()
+	return nil
+}
+
+//AddCameraView registers the camera with the camera manager.  The view
+// starts out disabled.  If a view with the same name exists it is replaced
+func AddCameraView(name string, camera *Camera, priority int) *CameraView {
+	RemoveCameraView(name)
+	view := &CameraView{
+		name:     name,
+		camera:   camera,
+		priority: priority,
+	}
+	cameraViews[name] = view
+	return view
+}
+
+//AddSceneCameras registers every camera node under the node by its node name
+func AddSceneCameras(node *Node, priority int) []*CameraView {
+	cameras := node.FindChild("", NodeTypeCamera)
+	views := make([]*CameraView, len(cameras))
+	for i := range cameras {
+		views[i] = AddCameraView(cameras[i].Name(), &Camera{cameras[i]}, priority)
+	}
+	return views
+}
+
+//CameraViewFromName returns the registered view with the passed in name
+func CameraViewFromName(name string) (*CameraView, bool) {
+	view, ok := cameraViews[name]
+	return view, ok
+}
+
+//RemoveCameraView removes the view from the camera manager.  If it was
+// the main view, the next highest priority view takes over immediately
+func RemoveCameraView(name string) {
+	view, ok := cameraViews[name]
+	if !ok {
+		return
+	}
+	view.enabled = false
+	delete(cameraViews, name)
+	if view == mainView {
+		chooseMainView(0)
+	}
+}
+
+func (v *CameraView) Name() string        { return v.name }
+func (v *CameraView) Camera() *Camera     { return v.camera }
+func (v *CameraView) Priority() int       { return v.priority }
+func (v *CameraView) Enabled() bool       { return v.enabled }
+func (v *CameraView) IsMain() bool        { return v == mainView }
+func (v *CameraView) Viewport() *Viewport { return v.viewport }
+
+//Enable makes the view available to be the main view, blending to it
+// over blend seconds if it becomes the main view
+func (v *CameraView) Enable(blend float64) {
+	enableCount++
+	v.order = enableCount
+	v.enabled = true
+	v.setupView()
+	chooseMainView(blend)
+}
+
+//Disable stops the view from being drawn.  If it was the main view the
+// render camera blends to the next highest priority view over blend seconds
+func (v *CameraView) Disable(blend float64) {
+	v.enabled = false
+	chooseMainView(blend)
+}
+
+//SetPriority changes the priority of the view, blending over blend
+// seconds if the main view changes
+func (v *CameraView) SetPriority(priority int, blend float64) {
+	v.priority = priority
+	chooseMainView(blend)
+}
+
+//FOV is the view's field of view in degrees, 0 if it uses the FOV setting
+func (v *CameraView) FOV() float32 { return v.fov }
+
+//SetFOV sets the view's field of view in degrees, 0 uses the FOV setting
+func (v *CameraView) SetFOV(fov float32) {
+	v.fov = fov
+	v.setupView()
+}
+
+//SetViewport draws the view to the area of the window in addition to the
+// main view.  A nil viewport draws the view as a main view again
+func (v *CameraView) SetViewport(viewport *Viewport) {
+	v.viewport = viewport
+	v.setupView()
+	chooseMainView(0)
+}
+
+//RenderToTexture draws the view into the texture each frame instead of
+// to the window.  The texture must be renderable, see NewCameraTexture.
+// A nil texture draws the view as a main view again
+func (v *CameraView) RenderToTexture(texture *Texture, width, height int) {
+	v.output = texture
+	v.outW, v.outH = width, height
+	if texture != nil {
+		v.camera.SetOutTexture(texture)
+	} else {
+		v.camera.SetOutTexture(&Texture{&Resource{0}})
+	}
+	v.setupView()
+	chooseMainView(0)
+}
+
+//NewCameraTexture creates a texture that camera views can be rendered to
+func NewCameraTexture(name string, width, height int) *Texture {
+	return NewVirtualTexture(name, width, height, horde3d.Formats_TEX_BGRA8,
+		horde3d.ResFlags_TexRenderable|horde3d.ResFlags_NoTexMipmaps)
+}
+
+//separate returns true if the view is drawn separately from the main view
+func (v *CameraView) separate() bool {
+	return v.viewport != nil || v.output != nil
+}
+
+func (v *CameraView) viewFOV() float32 {
+	if v.fov != 0 {
+		return v.fov
+	}
+	return defaultFOV
+}
+
+//setupView sets the projection and viewport of views drawn separately
+func (v *CameraView) setupView() {
+	if !v.separate() {
+		return
+	}
+	near := v.camera.H3DNode.NodeParamF(horde3d.Camera_NearPlaneF, 0)
+	far := v.camera.H3DNode.NodeParamF(horde3d.Camera_FarPlaneF, 0)
+
+	if v.output != nil {
+		v.camera.SetViewport(0, 0, v.outW, v.outH)
+		v.camera.SetupView(v.viewFOV(), float32(v.outW)/float32(maxInt(v.outH, 1)), near, far)
+		return
+	}
+
+	w, h := glfw.WindowSize()
+	x := int(v.viewport.X * float32(w))
+	width := int(v.viewport.Width * float32(w))
+	height := int(v.viewport.Height * float32(h))
+	//viewports are from the top, gl is from the bottom
+	y := h - int(v.viewport.Y*float32(h)) - height
+
+	v.camera.SetViewport(x, y, width, height)
+	v.camera.SetupView(v.viewFOV(), float32(width)/float32(maxInt(height, 1)), near, far)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+//SetMainCamera switches to the camera immediately, registering it with
+// the camera manager above every other view if it isn't already
+func SetMainCamera(camera *Camera) {
+	var view *CameraView
+	top := 0
+	for _, v := range cameraViews {
+		if v.camera.IsSame(camera.Node) {
+			view = v
+		}
+		if v.priority > top {
+			top = v.priority
+		}
+	}
+	if view == nil {
+		view = AddCameraView(camera.Name(), camera, top+1)
+	} else if view != mainView {
+		view.priority = top + 1
+	}
+	view.Enable(0)
+}
+
+//MainCamera returns the camera of the main view, or the render camera if
+// no views are enabled
+func MainCamera() *Camera {
+	if mainView == nil {
+		return renderCamera
+	}
+	return mainView.camera
+}
+
+//MainCameraView returns the view the render camera is following, nil if
+// no views are enabled
+func MainCameraView() *CameraView {
+	return mainView
+}
+
+//RenderCamera is the camera the main view is drawn through
+func RenderCamera() *Camera {
+	return renderCamera
+}
+
+//SetCameraFOV sets the field of view used by views that don't set their own
+func SetCameraFOV(fov float32) {
+	defaultFOV = fov
+	for _, v := range cameraViews {
+		v.setupView()
+	}
+}
+
+func CameraFOV() float32 {
+	return defaultFOV
+}
+
+//CameraBlending returns true if the render camera is blending between views
+func CameraBlending() bool {
+	return blendTween != nil && !blendTween.Done()
+}
+
+//chooseMainView picks the highest priority enabled view, blending to it
+// over blend seconds if it changed
+func chooseMainView(blend float64) {
+	var best *CameraView
+	for _, v := range cameraViews {
+		if !v.enabled || v.separate() {
+			continue
+		}
+		if best == nil || v.priority > best.priority ||
+			(v.priority == best.priority && v.order > best.order) {
+			best = v
+		}
+	}
+
+	if best == mainView {
+		return
+	}
+	mainView = best
+	if mainView == nil {
+		//nothing to follow, the render camera stays where it is
+		stopCameraBlend()
+		return
+	}
+	startCameraBlend(blend)
+}
+
+func startCameraBlend(duration float64) {
+	stopCameraBlend()
+	if duration <= 0 {
+		blendProgress = 1
+		return
+	}
+	blendFrom = viewState
+	blendProgress = 0
+	blendTween = TweenFloat(&blendProgress, 1, duration, EaseInOutSine)
+}
+
+func stopCameraBlend() {
+	if blendTween != nil {
+		blendTween.Stop()
+		blendTween = nil
+	}
+	blendProgress = 1
+}
+
+//updateCameras moves the render camera to the main view, blending from
+// the previous view if a blend is in progress
+func updateCameras() {
+	if mainView == nil {
+		return
+	}
+
+	var target cameraState
+	mainView.state(&target)
+
+	if blendProgress < 1 {
+		blendCameraState(&viewState, &blendFrom, &target, blendProgress)
+	} else {
+		viewState = target
+	}
+	applyCameraState(&viewState)
+
+	if pipeline := mainView.camera.Pipeline(); pipeline.H3DRes != renderCamera.Pipeline().H3DRes {
+		renderCamera.SetPipeline(pipeline)
+		resetView()
+	}
+	if mainView.camera.OcclusionCulling() != renderCamera.OcclusionCulling() {
+		renderCamera.SetOcclusionCulling(mainView.camera.OcclusionCulling())
+	}
+}
+
+//state reads the view's camera into the camera state
+func (v *CameraView) state(result *cameraState) {
+	matrix := v.camera.AbsoluteTransMat().Array()
+	result.translate = [3]float32{matrix[12], matrix[13], matrix[14]}
+	result.rotation.fromMatrix(matrix)
+	result.fov = v.viewFOV()
+	result.nearPlane = v.camera.H3DNode.NodeParamF(horde3d.Camera_NearPlaneF, 0)
+	result.farPlane = v.camera.H3DNode.NodeParamF(horde3d.Camera_FarPlaneF, 0)
+}
+
+func blendCameraState(result, from, to *cameraState, progress float32) {
+	for i := range result.translate {
+		result.translate[i] = lerp32(from.translate[i], to.translate[i], progress)
+	}
+	result.rotation.slerp(&from.rotation, &to.rotation, progress)
+	result.fov = lerp32(from.fov, to.fov, progress)
+	result.nearPlane = lerp32(from.nearPlane, to.nearPlane, progress)
+	result.farPlane = lerp32(from.farPlane, to.farPlane, progress)
+}
+
+//applyCameraState moves the render camera to the state, and updates its
+// projection if the fov or clip planes changed
+func applyCameraState(state *cameraState) {
+	matrix := tempCamMatrix.Array()
+	state.rotation.toMatrix(matrix)
+	matrix[12], matrix[13], matrix[14] = state.translate[0], state.translate[1], state.translate[2]
+	matrix[15] = 1
+	renderCamera.SetNodeTransMat(matrix)
+	renderCamera.updateFrame = -1
+
+	projection := [3]float32{state.fov, state.nearPlane, state.farPlane}
+	if projection != appliedProjection {
+		appliedProjection = projection
+		w, h := glfw.WindowSize()
+		renderCamera.SetupView(state.fov, float32(w)/float32(maxInt(h, 1)),
+			state.nearPlane, state.farPlane)
+	}
+}
+
+//renderCameras draws the textures of views rendered to textures, then the
+// main view, then any views drawn to a viewport over it
+func renderCameras() {
+	updateCameras()
+	for _, v := range cameraViews {
+		if v.enabled && v.output != nil {
+			horde3d.Render(v.camera.H3DNode)
+		}
+	}
+
+	horde3d.Render(renderCamera.H3DNode)
+
+	for _, v := range cameraViews {
+		if v.enabled && v.viewport != nil && v.output == nil {
+			horde3d.Render(v.camera.H3DNode)
+		}
+	}
+}
+
+func resetView() {
+	w, h := glfw.WindowSize()
+	resizeView(w, h)
+}
+
+func resizeView(w, h int) {
+	if h == 0 {
+		h = 1
+	}
+
+	renderCamera.SetViewport(0, 0, w, h)
+	renderCamera.SetupView(viewState.fov, float32(w)/float32(h), viewState.nearPlane, viewState.farPlane)
+	appliedProjection = [3]float32{viewState.fov, viewState.nearPlane, viewState.farPlane}
+	renderCamera.Pipeline().ResizeBuffers(w, h)
+
+	for _, v := range cameraViews {
+		v.setupView()
+	}
+	updateGuiScreenSize(w, h)
+}
+
+//quaternion is x, y, z, w, used for blending camera rotations
+type quaternion [4]float32
+
+var identityQuaternion = quaternion{0, 0, 0, 1}
+
+//fromMatrix sets the quaternion from the rotation of a column major matrix
+func (q *quaternion) fromMatrix(m *[16]float32) {
+	trace := m[0] + m[5] + m[10]
+	switch {
+	case trace > 0:
+		s := float32(math.Sqrt(float64(trace+1))) * 2
+		q[3] = s / 4
+		q[0] = (m[6] - m[9]) / s
+		q[1] = (m[8] - m[2]) / s
+		q[2] = (m[1] - m[4]) / s
+	case m[0] > m[5] && m[0] > m[10]:
+		s := float32(math.Sqrt(float64(1+m[0]-m[5]-m[10]))) * 2
+		q[3] = (m[6] - m[9]) / s
+		q[0] = s / 4
+		q[1] = (m[4] + m[1]) / s
+		q[2] = (m[8] + m[2]) / s
+	case m[5] > m[10]:
+		s := float32(math.Sqrt(float64(1+m[5]-m[0]-m[10]))) * 2
+		q[3] = (m[8] - m[2]) / s
+		q[0] = (m[4] + m[1]) / s
+		q[1] = s / 4
+		q[2] = (m[9] + m[6]) / s
+	default:
+		s := float32(math.Sqrt(float64(1+m[10]-m[0]-m[5]))) * 2
+		q[3] = (m[1] - m[4]) / s
+		q[0] = (m[8] + m[2]) / s
+		q[1] = (m[9] + m[6]) / s
+		q[2] = s / 4
+	}
+	q.normalize()
+}
+
+//toMatrix sets the upper 3x3 of the column major matrix to the rotation
+func (q *quaternion) toMatrix(m *[16]float32) {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	m[0] = 1 - 2*(y*y+z*z)
+	m[1] = 2 * (x*y + z*w)
+	m[2] = 2 * (x*z - y*w)
+	m[3] = 0
+	m[4] = 2 * (x*y - z*w)
+	m[5] = 1 - 2*(x*x+z*z)
+	m[6] = 2 * (y*z + x*w)
+	m[7] = 0
+	m[8] = 2 * (x*z + y*w)
+	m[9] = 2 * (y*z - x*w)
+	m[10] = 1 - 2*(x*x+y*y)
+	m[11] = 0
+}
+
+func (q *quaternion) normalize() {
+	length := float32(math.Sqrt(float64(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])))
+	if length == 0 {
+		*q = identityQuaternion
+		return
+	}
+	for i := range q {
+		q[i] /= length
+	}
+}
+
+//slerp sets the quaternion to the spherical interpolation between from and to
+func (q *quaternion) slerp(from, to *quaternion, progress float32) {
+	end := *to
+	cos := from[0]*end[0] + from[1]*end[1] + from[2]*end[2] + from[3]*end[3]
+	//take the short way around
+	if cos < 0 {
+		cos = -cos
+		for i := range end {
+			end[i] = -end[i]
+		}
+	}
+
+	fromScale, toScale := 1-progress, progress
+	if cos < 0.9995 {
+		angle := math.Acos(float64(cos))
+		sin := math.Sin(angle)
+		fromScale = float32(math.Sin(float64(1-progress)*angle) / sin)
+		toScale = float32(math.Sin(float64(progress)*angle) / sin)
+	}
+
+	for i := range q {
+		q[i] = from[i]*fromScale + end[i]*toScale
+	}
+	q.normalize()
+}
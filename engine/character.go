@@ -0,0 +1,376 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gonewton/newton"
+	"math"
+)
+
+const (
+	characterSkin      = 0.01 //gap kept between the capsule and what it hits
+	characterMaxSlides = 4    //times a move can slide along what it hits
+	characterGroundGap = 0.05 //distance below the feet that still counts as on the ground
+)
+
+//characters are checked against trigger volumes
+var characters []*CharacterController
+
+//CharacterSettings are the dimensions and movement limits of a character
+type CharacterSettings struct {
+	Radius       float32
+	Height       float32
+	CrouchHeight float32
+	EyeHeight    float32 //from the feet when standing, scaled when crouching
+	StepHeight   float32 //tallest ledge that can be walked up
+	MaxSlope     float32 //steepest slope in degrees that can be stood on
+	Gravity      float32
+	JumpSpeed    float32
+}
+
+//DefaultCharacterSettings are roughly human sized
+var DefaultCharacterSettings = CharacterSettings{
+	Radius:       0.4,
+	Height:       1.8,
+	CrouchHeight: 1.0,
+	EyeHeight:    1.65,
+	StepHeight:   0.35,
+	MaxSlope:     45,
+	Gravity:      GRAVITY,
+	JumpSpeed:    5,
+}
+
+//CharacterController is a kinematic capsule moved through the physics world.
+// Moves are swept against the world and slide along what they hit.  Ledges
+// up to the step height are climbed, slopes steeper than the max slope
+// can't be stood on, and gravity pulls the character down when it isn't
+// on the ground.  The node is moved to the character's eye position
+type CharacterController struct {
+	Node     *Node
+	Settings CharacterSettings
+
+	standShape  *newton.Collision
+	crouchShape *newton.Collision
+	feet        [3]float32
+	velocity    [3]float32
+	onGround    bool
+	crouching   bool
+	ground      [3]float32 //normal of the ground when on it
+	minGroundY  float32    //smallest y of a normal that can be stood on
+	enabled     bool       //disabled characters aren't seen by trigger volumes
+}
+
+//NewCharacterController creates a character with its feet below the
+// node's current position by the eye height
+func NewCharacterController(node *Node, settings CharacterSettings) *CharacterController {
+	c := &CharacterController{
+		Node:       node,
+		Settings:   settings,
+		minGroundY: float32(math.Cos(float64(settings.MaxSlope) * math.Pi / 180)),
+		enabled:    true,
+	}
+	c.standShape = characterCapsule(settings.Radius, settings.Height)
+	c.crouchShape = characterCapsule(settings.Radius, settings.CrouchHeight)
+
+	matrix := node.AbsoluteTransMat().Array()
+	c.feet = [3]float32{matrix[12], matrix[13] - settings.EyeHeight, matrix[14]}
+	c.checkGround()
+	characters = append(characters, c)
+	return c
+}
+
+//characterCapsule creates an upright capsule, newton capsules lie along x
+func characterCapsule(radius, height float32) *newton.Collision {
+	offset := &[16]float32{
+		0, 1, 0, 0,
+		-1, 0, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+	return phWorld.CreateCapsule(radius, height, 0, offset)
+}
+
+func (c *CharacterController) OnGround() bool           { return c.onGround }
+func (c *CharacterController) Crouching() bool          { return c.crouching }
+func (c *CharacterController) Velocity() [3]float32     { return c.velocity }
+func (c *CharacterController) Feet() [3]float32         { return c.feet }
+func (c *CharacterController) GroundNormal() [3]float32 { return c.ground }
+func (c *CharacterController) Enabled() bool            { return c.enabled }
+
+//SetEnabled sets whether trigger volumes see the character, such as when
+// it isn't being moved
+func (c *CharacterController) SetEnabled(enabled bool) { c.enabled = enabled }
+
+//SetFeet moves the character without sweeping
+func (c *CharacterController) SetFeet(position [3]float32) {
+	c.feet = position
+	c.velocity = [3]float32{}
+	c.checkGround()
+}
+
+func (c *CharacterController) height() float32 {
+	if c.crouching {
+		return c.Settings.CrouchHeight
+	}
+	return c.Settings.Height
+}
+
+func (c *CharacterController) shape() *newton.Collision {
+	if c.crouching {
+		return c.crouchShape
+	}
+	return c.standShape
+}
+
+//EyePosition is where the node is placed, the eye height above the feet
+func (c *CharacterController) EyePosition() [3]float32 {
+	eye := c.Settings.EyeHeight * c.height() / c.Settings.Height
+	return [3]float32{c.feet[0], c.feet[1] + eye, c.feet[2]}
+}
+
+//Jump launches the character upwards if it's on the ground
+func (c *CharacterController) Jump() bool {
+	if !c.onGround {
+		return false
+	}
+	c.velocity[1] = c.Settings.JumpSpeed
+	c.onGround = false
+	return true
+}
+
+//SetCrouch crouches or stands the character.  Returns false if there
+// isn't room to stand up
+func (c *CharacterController) SetCrouch(crouch bool) bool {
+	if crouch == c.crouching {
+		return true
+	}
+	if !crouch {
+		//check for headroom before standing
+		rise := c.Settings.Height - c.Settings.CrouchHeight
+		if fraction, _, hit := c.sweep(c.crouchShape, c.Settings.CrouchHeight, c.feet,
+			[3]float32{0, rise, 0}); hit && fraction < 1 {
+			return false
+		}
+	}
+	c.crouching = crouch
+	return true
+}
+
+//Move moves the character by the horizontal velocity over dt seconds,
+// along with its vertical velocity from jumping and falling
+func (c *CharacterController) Move(walk [3]float32, dt float32) {
+	if dt <= 0 {
+		return
+	}
+
+	if c.onGround && c.velocity[1] <= 0 {
+		c.velocity[1] = 0
+	} else {
+		c.velocity[1] += c.Settings.Gravity * dt
+	}
+	c.velocity[0], c.velocity[2] = walk[0], walk[2]
+
+	horizontal := [3]float32{walk[0] * dt, 0, walk[2] * dt}
+	if c.onGround {
+		//keep walking along the slope of the ground
+		horizontal = projectOnPlane(horizontal, c.ground)
+	}
+	if vecLength(horizontal) > 0 {
+		c.moveHorizontal(horizontal)
+	}
+
+	vertical := [3]float32{0, c.velocity[1] * dt, 0}
+	if vertical[1] != 0 {
+		_, normal, hit := c.slide(vertical)
+		if hit {
+			if normal[1] >= c.minGroundY && c.velocity[1] < 0 {
+				c.velocity[1] = 0
+			} else if normal[1] < 0 && c.velocity[1] > 0 {
+				//hit the ceiling
+				c.velocity[1] = 0
+			}
+		}
+	}
+
+	wasOnGround := c.onGround
+	c.checkGround()
+	if wasOnGround && !c.onGround && c.velocity[1] <= 0 {
+		//stay on the ground walking down slopes and steps
+		c.snapToGround()
+	}
+	c.placeNode()
+}
+
+//moveHorizontal tries stepping up over the move first, and keeps the step
+// if it gets further than sliding along the ground
+func (c *CharacterController) moveHorizontal(move [3]float32) {
+	start := c.feet
+	c.slide(move)
+	slid := c.feet
+
+	if !c.onGround || c.Settings.StepHeight <= 0 {
+		return
+	}
+	if distanceSquared(start, slid) >= dot(move, move)*0.99 {
+		//nothing was in the way
+		return
+	}
+
+	c.feet = start
+	up, _, _ := c.slide([3]float32{0, c.Settings.StepHeight, 0})
+	c.slide(move)
+	fraction, normal, hit := c.sweep(c.shape(), c.height(), c.feet, [3]float32{0, -up, 0})
+	stepped := c.feet
+	stepped[1] -= up * fraction
+
+	if !hit || normal[1] < c.minGroundY ||
+		horizontalDistanceSquared(start, stepped) <= horizontalDistanceSquared(start, slid) {
+		c.feet = slid
+		return
+	}
+	c.feet = stepped
+	c.feet[1] += characterSkin
+}
+
+//slide moves the character, sliding along anything it hits.  Returns how
+// far it moved in the direction of the first move, and the normal of the
+// last thing hit
+func (c *CharacterController) slide(move [3]float32) (float32, [3]float32, bool) {
+	moved := float32(0)
+	length := vecLength(move)
+	var lastNormal [3]float32
+	hitAny := false
+
+	for i := 0; i < characterMaxSlides && vecLength(move) > 1e-5; i++ {
+		fraction, normal, hit := c.sweep(c.shape(), c.height(), c.feet, move)
+		if !hit {
+			c.feet = add(c.feet, move)
+			if i == 0 {
+				moved = length
+			}
+			break
+		}
+
+		hitAny = true
+		lastNormal = normal
+		//back off so the capsule isn't touching what it hit
+		travel := vecLength(move) * fraction
+		if travel > characterSkin {
+			step := scale(move, (travel-characterSkin)/travel*fraction)
+			c.feet = add(c.feet, step)
+			if i == 0 {
+				moved = vecLength(step)
+			}
+		}
+
+		//slide the rest of the move along what was hit, walls don't push
+		// the character up, steep slopes slide it down
+		remaining := scale(move, 1-fraction)
+		if normal[1] < c.minGroundY && normal[1] > 0 {
+			normal[1] = 0
+			if n, ok := normalizeVec(normal); ok {
+				normal = n
+			}
+		}
+		move = projectOnPlane(remaining, normal)
+	}
+	return moved, lastNormal, hitAny
+}
+
+//checkGround looks for ground just below the feet
+func (c *CharacterController) checkGround() {
+	fraction, normal, hit := c.sweep(c.shape(), c.height(), c.feet,
+		[3]float32{0, -characterGroundGap, 0})
+	c.onGround = hit && fraction < 1 && normal[1] >= c.minGroundY && c.velocity[1] <= 0
+	if c.onGround {
+		c.ground = normal
+	} else {
+		c.ground = [3]float32{0, 1, 0}
+	}
+}
+
+//snapToGround drops the character onto ground within a step below it
+func (c *CharacterController) snapToGround() {
+	drop := c.Settings.StepHeight
+	fraction, normal, hit := c.sweep(c.shape(), c.height(), c.feet, [3]float32{0, -drop, 0})
+	if !hit || normal[1] < c.minGroundY {
+		return
+	}
+	c.feet[1] -= drop*fraction - characterSkin
+	c.onGround = true
+	c.ground = normal
+}
+
+//placeNode moves the node to the eye, the feet and eye are in world space
+// so it's placed with its absolute matrix
+func (c *CharacterController) placeNode() {
+	matrix := *c.Node.AbsoluteTransMat().Array()
+	eye := c.EyePosition()
+	matrix[12], matrix[13], matrix[14] = eye[0], eye[1], eye[2]
+	c.Node.SetAbsoluteTransMat(&matrix)
+}
+
+//sweep casts the capsule standing on the feet position along the move.
+// Returns the fraction of the move made before hitting something
+func (c *CharacterController) sweep(shape *newton.Collision, height float32,
+	feet, move [3]float32) (fraction float32, normal [3]float32, hit bool) {
+	matrix := capsuleMatrix(feet, height)
+	end := [3]float32{matrix[12] + move[0], matrix[13] + move[1], matrix[14] + move[2]}
+
+	fraction, info, hit := convexCast(shape, matrix, end, CollidesWith(LayerPlayer))
+	if !hit {
+		return 1, normal, false
+	}
+	normal = [3]float32{info.Normal[0], info.Normal[1], info.Normal[2]}
+	return fraction, normal, true
+}
+
+//capsuleMatrix places the centre of the capsule above the feet
+func capsuleMatrix(feet [3]float32, height float32) *[16]float32 {
+	return positionMatrix([3]float32{feet[0], feet[1] + height/2, feet[2]})
+}
+
+func (c *CharacterController) capsuleMatrix() *[16]float32 {
+	return capsuleMatrix(c.feet, c.height())
+}
+
+func add(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+func scale(v [3]float32, s float32) [3]float32 {
+	return [3]float32{v[0] * s, v[1] * s, v[2] * s}
+}
+
+func dot(a, b [3]float32) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func vecLength(v [3]float32) float32 {
+	return float32(math.Sqrt(float64(dot(v, v))))
+}
+
+func normalizeVec(v [3]float32) ([3]float32, bool) {
+	length := vecLength(v)
+	if length < 1e-6 {
+		return v, false
+	}
+	return scale(v, 1/length), true
+}
+
+//projectOnPlane removes the part of v along the plane's normal
+func projectOnPlane(v, normal [3]float32) [3]float32 {
+	return add(v, scale(normal, -dot(v, normal)))
+}
+
+func distanceSquared(a, b [3]float32) float32 {
+	d := add(a, scale(b, -1))
+	return dot(d, d)
+}
+
+func horizontalDistanceSquared(a, b [3]float32) float32 {
+	x, z := a[0]-b[0], a[2]-b[2]
+	return x*x + z*z
+}
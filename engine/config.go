@@ -8,21 +8,89 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"math"
 	"os"
 	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
+//Value types for config settings
+const (
+	ConfigInt = iota
+	ConfigFloat
+	ConfigBool
+	ConfigString
+)
+
+//ConfigSetting describes a single entry in a config file
+// Min and Max only apply to numeric settings, and are ignored if Max <= Min
+// RequiresRestart lets options screens warn that a change won't take effect
+// until the engine is restarted
+type ConfigSetting struct {
+	Key             string
+	Type            int
+	Default         interface{}
+	Min, Max        float64
+	Description     string
+	RequiresRestart bool
+}
+
+//ConfigError is a problem with a single entry in a config file
+type ConfigError struct {
+	FileName string
+	Key      string
+	Reason   string
+}
+
+func (e *ConfigError) Error() string {
+	return "Config entry " + e.Key + " in " + e.FileName + " is invalid: " + e.Reason
+}
+
+//ConfigErrors is the list of every invalid entry found when validating
+// a config file
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+var configSchemas = make(map[string][]*ConfigSetting)
+
+//SetConfigSchema declares the settings expected in the named config file
+// i.e. "controls.cfg".  Must be called before the config file is created.
+// Missing entries are written back with their defaults when the file is loaded
+// so config files upgrade themselves between versions
+func SetConfigSchema(cfgName string, settings []*ConfigSetting) {
+	configSchemas[cfgName] = settings
+}
+
 type Config struct {
-	Name           string
-	FileName       string
-	values         map[string]interface{}
-	onWriteHandler ConfigOnWriteHandler
+	Name          string
+	FileName      string
+	values        map[string]interface{}
+	schema        []*ConfigSetting
+	schemaKeys    map[string]*ConfigSetting
+	subscriptions []*ConfigSubscription
+	watching      bool
+	modTime       time.Time
 }
 
 func NewCfg(fileName string) (*Config, error) {
 	cfg := new(Config)
 	cfg.Name = fileName
 	cfg.values = make(map[string]interface{})
+	cfg.schema = configSchemas[fileName]
+	cfg.schemaKeys = make(map[string]*ConfigSetting)
+	for i := range cfg.schema {
+		cfg.schemaKeys[cfg.schema[i].Key] = cfg.schema[i]
+	}
 	//if just a filename with no path is passed in,
 	// then combine it with the userDir
 	if !path.IsAbs(fileName) {
@@ -48,7 +116,7 @@ func NewStandardCfg() (*Config, error) {
 		if os.IsNotExist(err) {
 			//file doesn't exist
 			// create one with default values
-			defaultConfigHandler(cfg)
+			cfg.setDefaults()
 			if err = cfg.Write(); err != nil {
 				return nil, err
 			}
@@ -71,7 +139,7 @@ func NewControlCfg() (*Config, error) {
 		if os.IsNotExist(err) {
 			//file doesn't exist
 			// create one with default values
-			defaultConfigHandler(cfg)
+			cfg.setDefaults()
 			if err = cfg.Write(); err != nil {
 				return nil, err
 			}
@@ -95,6 +163,29 @@ func SetDefaultConfigHandler(function DefaultConfigHandler) {
 	defaultConfigHandler = function
 }
 
+//setDefaults sets the schema defaults, then lets the game's default
+// handler override them.  Defaults are stored as they'd be read from
+// the file, so later changes to the file compare equal to them
+func (cfg *Config) setDefaults() {
+	for i := range cfg.schema {
+		cfg.values[cfg.schema[i].Key] = cfgNormalize(cfg.schema[i].Default)
+	}
+	if defaultConfigHandler != nil {
+		defaultConfigHandler(cfg)
+	}
+}
+
+//Setting returns the schema entry for the given key
+func (cfg *Config) Setting(name string) (*ConfigSetting, bool) {
+	setting, ok := cfg.schemaKeys[name]
+	return setting, ok
+}
+
+//Settings returns the schema entries in the order they were declared
+func (cfg *Config) Settings() []*ConfigSetting {
+	return cfg.schema
+}
+
 //Loads a specific config file at a specific location
 func (cfg *Config) Load() error {
 	if cfg.FileName == "" {
@@ -112,9 +203,112 @@ func (cfg *Config) Load() error {
 		RaiseError(err)
 		return err
 	}
+
+	if info, err := os.Stat(cfg.FileName); err == nil {
+		cfg.modTime = info.ModTime()
+	}
+
+	if cfg.upgrade() {
+		if err = cfg.write(); err != nil {
+			RaiseError(err)
+			return err
+		}
+	}
 	return nil
 }
 
+//Validate checks every entry in the config against its schema
+// returns ConfigErrors with an entry for every invalid key, or nil
+func (cfg *Config) Validate() error {
+	var errs ConfigErrors
+	for _, setting := range cfg.schema {
+		value, ok := cfg.values[setting.Key]
+		if !ok {
+			errs = append(errs, &ConfigError{cfg.Name, setting.Key, "entry is missing"})
+			continue
+		}
+		if reason := setting.check(value); reason != "" {
+			errs = append(errs, &ConfigError{cfg.Name, setting.Key, reason})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+//upgrade adds missing schema entries and replaces invalid ones
+// raising an error for each invalid entry.  Returns true if any
+// values changed and the file should be written back
+func (cfg *Config) upgrade() bool {
+	changed := false
+	for _, setting := range cfg.schema {
+		value, ok := cfg.values[setting.Key]
+		if !ok {
+			cfg.values[setting.Key] = cfgNormalize(setting.Default)
+			changed = true
+			continue
+		}
+
+		if reason := setting.check(value); reason != "" {
+			RaiseError(&ConfigError{cfg.Name, setting.Key, reason})
+			cfg.values[setting.Key] = cfgNormalize(setting.fix(value))
+			changed = true
+		}
+	}
+	return changed
+}
+
+//check returns the reason the value is invalid for this setting
+// or an empty string if it's valid
+func (s *ConfigSetting) check(value interface{}) string {
+	switch s.Type {
+	case ConfigInt, ConfigFloat:
+		f, ok := cfgToFloat(value)
+		if !ok {
+			return "expected a number"
+		}
+		if s.Type == ConfigInt && f != math.Trunc(f) {
+			return "expected a whole number"
+		}
+		if s.Max > s.Min && (f < s.Min || f > s.Max) {
+			return "value " + strconv.FormatFloat(f, 'g', -1, 64) + " is outside of the range " +
+				strconv.FormatFloat(s.Min, 'g', -1, 64) + " to " + strconv.FormatFloat(s.Max, 'g', -1, 64)
+		}
+	case ConfigBool:
+		if _, ok := cfgToBool(value); !ok {
+			return "expected true or false"
+		}
+	case ConfigString:
+		if _, ok := cfgToString(value); !ok {
+			return "expected a string"
+		}
+	}
+	return ""
+}
+
+//fix returns a valid value for this setting, rounding fractional ints and
+// clamping numbers that are out of range, and falling back to the default otherwise
+func (s *ConfigSetting) fix(value interface{}) interface{} {
+	if s.Type == ConfigInt || s.Type == ConfigFloat {
+		if f, ok := cfgToFloat(value); ok {
+			if s.Type == ConfigInt {
+				f = math.Floor(f + 0.5)
+			}
+			if s.Max > s.Min {
+				if f < s.Min {
+					return s.Min
+				}
+				if f > s.Max {
+					return s.Max
+				}
+			}
+			return f
+		}
+	}
+	return s.Default
+}
+
 func (cfg *Config) Value(name string) interface{} {
 	return cfg.values[name]
 }
@@ -144,75 +338,286 @@ func handleMissing(name string) (value interface{}) {
 	return nil
 }
 
-func (cfg *Config) Int(name string) int {
-	value, ok := cfg.values[name].(float64)
-	if !ok {
-		value := handleMissing(name)
-		if value != nil {
-			return value.(int)
+//lookup returns the value for the given name, falling back to the
+// schema default, then any default value handlers
+func (cfg *Config) lookup(name string, convert func(interface{}) (interface{}, bool)) interface{} {
+	if value, ok := cfg.values[name]; ok {
+		if converted, ok := convert(value); ok {
+			return converted
 		}
+		RaiseError(&ConfigError{cfg.Name, name, "value is the wrong type. Using default."})
+	}
+
+	if setting, ok := cfg.schemaKeys[name]; ok {
+		if converted, ok := convert(setting.Default); ok {
+			return converted
+		}
+	}
+
+	if value := handleMissing(name); value != nil {
+		if converted, ok := convert(value); ok {
+			return converted
+		}
+	}
+	return nil
+}
 
+func (cfg *Config) Int(name string) int {
+	value := cfg.lookup(name, func(v interface{}) (interface{}, bool) { return cfgToFloat(v) })
+	if value == nil {
 		return 0
 	}
-	return int(value)
+	return int(value.(float64))
 }
 
 func (cfg *Config) String(name string) string {
-	value, ok := cfg.values[name].(string)
-	if !ok {
-		value := handleMissing(name)
-		if value != nil {
-			return value.(string)
-		}
+	value := cfg.lookup(name, func(v interface{}) (interface{}, bool) { return cfgToString(v) })
+	if value == nil {
 		return ""
 	}
-	return value
+	return value.(string)
 }
 
 func (cfg *Config) Bool(name string) bool {
-	value, ok := cfg.values[name].(bool)
-	if !ok {
-		value := handleMissing(name)
-		if value != nil {
-			return value.(bool)
-		}
+	value := cfg.lookup(name, func(v interface{}) (interface{}, bool) { return cfgToBool(v) })
+	if value == nil {
 		return false
 	}
-	return value
+	return value.(bool)
 }
 
 func (cfg *Config) Float(name string) float32 {
-	value, ok := cfg.values[name].(float64)
-	if !ok {
-		value := handleMissing(name)
-		if value != nil {
-			return value.(float32)
-		}
+	value := cfg.lookup(name, func(v interface{}) (interface{}, bool) { return cfgToFloat(v) })
+	if value == nil {
 		return 0.0
 	}
-	return float32(value)
+	return float32(value.(float64))
+}
+
+//cfgToFloat converts any numeric type to a float64.  JSON numbers are
+// always float64, but values set in code or by handlers may not be
+func cfgToFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func cfgToBool(value interface{}) (bool, bool) {
+	v, ok := value.(bool)
+	return v, ok
+}
+
+func cfgToString(value interface{}) (string, bool) {
+	v, ok := value.(string)
+	return v, ok
 }
 
-func (cfg *Config) SetValue(name string, value interface{}) {
+//cfgNormalize returns numbers as float64, the same as they're read from
+// the file, so values set in code compare equal to loaded ones
+func cfgNormalize(value interface{}) interface{} {
+	if f, ok := cfgToFloat(value); ok {
+		return f
+	}
+	return value
+}
+
+//SetValue sets the value of the given entry, and notifies any
+// change handlers registered for it if the value is different.
+// Values for entries in the schema are checked against it, and
+// aren't set if they're invalid
+func (cfg *Config) SetValue(name string, value interface{}) error {
+	if setting, ok := cfg.schemaKeys[name]; ok {
+		if reason := setting.check(value); reason != "" {
+			err := &ConfigError{cfg.Name, name, reason}
+			RaiseError(err)
+			return err
+		}
+	}
+
+	value = cfgNormalize(value)
+	old, ok := cfg.values[name]
 	cfg.values[name] = value
+	if !ok || !reflect.DeepEqual(cfgNormalize(old), value) {
+		cfg.notifyChange(name)
+	}
+	return nil
 }
 
+//Write writes the config to disk and notifies all write handlers
 func (cfg *Config) Write() error {
+	if err := cfg.write(); err != nil {
+		return err
+	}
+	cfg.notifyWrite()
+	return nil
+}
+
+func (cfg *Config) write() error {
 	data, err := json.MarshalIndent(cfg.values, "", "    ")
 	if err != nil {
 		return err
 	}
 	err = ioutil.WriteFile(cfg.FileName, data, 0644)
+	if err != nil {
+		return err
+	}
 
-	return err
-
+	//don't let the file watcher pick up our own changes
+	if info, err := os.Stat(cfg.FileName); err == nil {
+		cfg.modTime = info.ModTime()
+	}
+	return nil
 }
 
 type ConfigOnWriteHandler func(cfg *Config)
 
+//ConfigOnChangeHandler is called with the name of the entry that changed
+type ConfigOnChangeHandler func(cfg *Config, name string)
+
+//ConfigSubscription is returned when registering a handler on a config
+// and is used to unregister it
+type ConfigSubscription struct {
+	cfg      *Config
+	name     string
+	onWrite  ConfigOnWriteHandler
+	onChange ConfigOnChangeHandler
+}
+
+//Unsubscribe stops the handler from being called
+func (s *ConfigSubscription) Unsubscribe() {
+	if s.cfg == nil {
+		return
+	}
+	subs := s.cfg.subscriptions
+	for i := range subs {
+		if subs[i] == s {
+			s.cfg.subscriptions = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	s.cfg = nil
+}
+
 //RegisterOnWriteHandler registers a function to be called when
-// this config file is written.  So that if changes are made, 
+// this config file is written.  So that if changes are made,
 // the consumers of the config can get the latest values
-func (cfg *Config) RegisterOnWriteHandler(handler ConfigOnWriteHandler) {
-	cfg.onWriteHandler = handler
+// Any number of handlers can be registered
+func (cfg *Config) RegisterOnWriteHandler(handler ConfigOnWriteHandler) *ConfigSubscription {
+	sub := &ConfigSubscription{cfg: cfg, onWrite: handler}
+	cfg.subscriptions = append(cfg.subscriptions, sub)
+	return sub
+}
+
+//RegisterOnChangeHandler registers a function to be called when the
+// named entry's value changes, either from SetValue or from the file being
+// changed outside of the game.  An empty name is called for every entry
+func (cfg *Config) RegisterOnChangeHandler(name string, handler ConfigOnChangeHandler) *ConfigSubscription {
+	sub := &ConfigSubscription{cfg: cfg, name: name, onChange: handler}
+	cfg.subscriptions = append(cfg.subscriptions, sub)
+	return sub
+}
+
+func (cfg *Config) notifyWrite() {
+	//copy, so handlers can unsubscribe while being notified
+	subs := append([]*ConfigSubscription(nil), cfg.subscriptions...)
+	for i := range subs {
+		if subs[i].onWrite != nil && subs[i].cfg != nil {
+			subs[i].onWrite(cfg)
+		}
+	}
+}
+
+func (cfg *Config) notifyChange(name string) {
+	subs := append([]*ConfigSubscription(nil), cfg.subscriptions...)
+	for i := range subs {
+		if subs[i].onChange != nil && subs[i].cfg != nil &&
+			(subs[i].name == "" || subs[i].name == name) {
+			subs[i].onChange(cfg, name)
+		}
+	}
+}
+
+//File watching
+const configWatchInterval = 1.0 //seconds
+
+var watchedConfigs []*Config
+var configLastWatch float64
+
+//Watch checks the config file for changes made outside of the game
+// i.e. from a text editor, and reloads it. Change and write handlers are
+// notified of any entries that changed
+func (cfg *Config) Watch() {
+	if cfg.watching {
+		return
+	}
+	cfg.watching = true
+	watchedConfigs = append(watchedConfigs, cfg)
+}
+
+//StopWatching stops checking the config file for outside changes
+func (cfg *Config) StopWatching() {
+	for i := range watchedConfigs {
+		if watchedConfigs[i] == cfg {
+			watchedConfigs = append(watchedConfigs[:i], watchedConfigs[i+1:]...)
+			break
+		}
+	}
+	cfg.watching = false
+}
+
+func updateConfigWatch() {
+	if Time()-configLastWatch < configWatchInterval {
+		return
+	}
+	configLastWatch = Time()
+
+	for i := range watchedConfigs {
+		watchedConfigs[i].checkForChanges()
+	}
+}
+
+func (cfg *Config) checkForChanges() {
+	info, err := os.Stat(cfg.FileName)
+	if err != nil || !info.ModTime().After(cfg.modTime) {
+		return
+	}
+	//only try a bad file once per change
+	cfg.modTime = info.ModTime()
+
+	old := make(map[string]interface{}, len(cfg.values))
+	for k, v := range cfg.values {
+		old[k] = v
+	}
+
+	//start from an empty map so removed entries fall back to defaults
+	cfg.values = make(map[string]interface{})
+	if err = cfg.Load(); err != nil {
+		cfg.values = old
+		return
+	}
+
+	for k, v := range cfg.values {
+		if oldValue, ok := old[k]; !ok || !reflect.DeepEqual(oldValue, v) {
+			cfg.notifyChange(k)
+		}
+	}
+	for k := range old {
+		if _, ok := cfg.values[k]; !ok {
+			cfg.notifyChange(k)
+		}
+	}
+
+	cfg.notifyWrite()
 }
@@ -11,19 +11,7 @@ import (
 	"runtime"
 )
 
-//Used to hold resize parms between cameras
-// the render cam will lookup near and far plane,
-// but FOV will have to be set separately
-type renderCam struct {
-	camera      *Camera
-	fallbackCam *Camera
-	fov         float32
-	nearPlane   float32
-	farPlane    float32
-}
-
 var Root *Node
-var mainCam *renderCam
 var running bool
 var frames int
 var startTime float64
@@ -59,24 +47,10 @@ func Init(name string) error {
 		return err
 	}
 
-	var mode int
-	if cfg.Bool("Fullscreen") {
-		mode = glfw.Fullscreen
-	} else {
-		mode = glfw.Windowed
-	}
-
-	if err := glfw.OpenWindow(cfg.Int("WindowWidth"),
-		cfg.Int("WindowHeight"), 8, 8, 8, 8,
-		cfg.Int("WindowDepth"), 8,
-		mode); err != nil {
+	if err = openWindow(videoSettingsFromCfg(cfg)); err != nil {
 		return err
 	}
 
-	glfw.SetSwapInterval(cfg.Int("VSync"))
-	glfw.SetWindowTitle(name)
-	glfw.Disable(glfw.MouseCursor)
-
 	if !horde3d.Init() {
 		horde3d.DumpMessages()
 		return errors.New("Error starting Horde3D.  Check Horde3D_log.html for more information")
@@ -91,29 +65,26 @@ func Init(name string) error {
 	}
 	controlCfg.Load()
 
+	//pick up changes made to the config files outside of the game
+	standardCfg.Watch()
+	controlCfg.Watch()
+
 	initInput()
 
 	InitPhysics()
 
-	//setup base camera
-	pipeline, err := loadDefaultPipeline()
-	if err != nil {
-		panic(err)
-	}
-
 	initGui()
+	initLanguage(cfg)
+	setWindowCallbacks()
 
-	mainCam = new(renderCam)
-	mainCam.fallbackCam = AddCamera(Root, "FallbackCamera", pipeline)
-	mainCam.camera = mainCam.fallbackCam
-	mainCam.fov = 45
-
-	resizeView(Cfg().Int("WindowWidth"), Cfg().Int("WindowHeight"))
+	//setup the render camera
+	if err = initCameras(cfg.Float("FOV")); err != nil {
+		return err
+	}
 
 	//Music and Audio
 	initMusic()
 	initAudio(cfg.String("AudioDevice"), cfg.Int("MaxAudioSources"), cfg.Int("MaxAudioBufferSize"))
-	glfw.SetWindowSizeCallback(resizeView)
 
 	return nil
 
@@ -130,14 +101,16 @@ func StartMainLoop() {
 		// use physics fixed loop.
 		// collect and lerp all node updates
 		frames++
+		updateConfigWatch()
 		joyUpdate()
 		if !paused {
 			runTasks()
 			updateAudio()
 			updatePhysics()
 		}
+		updateResourceLoaders()
 		updateGui()
-		horde3d.Render(mainCam.camera.H3DNode)
+		renderCameras()
 		horde3d.FinalizeFrame()
 		horde3d.ClearOverlays()
 		glfw.SwapBuffers()
@@ -162,40 +135,6 @@ func Fps() float64 {
 	return fps
 }
 
-func SetMainCamera(camera *Camera) {
-	mainCam.camera = camera
-	mainCam.nearPlane = camera.H3DNode.NodeParamF(horde3d.Camera_NearPlaneF, 0)
-	mainCam.farPlane = camera.H3DNode.NodeParamF(horde3d.Camera_FarPlaneF, 0)
-	resetView()
-}
-
-func SetCameraFOV(fov float32) {
-	mainCam.fov = fov
-	resetView()
-}
-
-func MainCamera() *Camera {
-	return mainCam.camera
-}
-
-func resetView() {
-	w, h := glfw.WindowSize()
-	resizeView(w, h)
-}
-
-func resizeView(w, h int) {
-	if h == 0 {
-		h = 1
-	}
-
-	mainCam.camera.SetViewport(0, 0, w, h)
-
-	mainCam.camera.SetupView(mainCam.fov, float32(w)/float32(h), mainCam.nearPlane, mainCam.farPlane)
-	mainCam.camera.Pipeline().ResizeBuffers(w, h)
-	updateGuiScreenSize(w, h)
-
-}
-
 func Time() float64 {
 	return glfw.Time()
 }
@@ -212,10 +151,15 @@ func GameTime() float64 {
 //Clear clears all rendering, physics, and sound resources, nodes, etc
 func ClearAll() {
 	removeAllTasks()
+	ResumeGameInput()
+	cancelResourceLoaders()
 	UnloadAllGuis()
+	RemoveAllHuds()
 	clearAllAudio()
 	clearAllPhysics()
 	//horde3d.Clear()
+	unloadFonts()
+	unloadScreenFade()
 
 	children := Root.Children()
 	for i := range children {
@@ -230,11 +174,18 @@ func ClearAll() {
 	horde3d.ReleaseUnusedResources()
 
 	initDebugPrint()
+	//rebuild font atlases for any text that's still in use
+	resetAllText()
 
-	SetMainCamera(mainCam.fallbackCam)
+	if err := resetCameras(); err != nil {
+		RaiseError(err)
+	}
 }
 
 func Pause() {
+	if paused {
+		return
+	}
 	paused = true
 	pauseStart = Time()
 	pauseAllAudio()
@@ -242,6 +193,9 @@ func Pause() {
 }
 
 func Resume() {
+	if !paused {
+		return
+	}
 	paused = false
 	pausedTime += Time() - pauseStart
 	resumeAllAudio()
@@ -0,0 +1,136 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gohorde/horde3d"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+//Full screen fade drawn over everything, used to hide scene and camera changes
+
+var (
+	fadeColor       = NewColor(0, 0, 0, 255)
+	fadeAlpha       float32
+	fadeTween       *Tween
+	fadeQueue       []func() //fades started while another was running
+	fadeTexture     *Texture
+	fadeMaterial    *Material
+	fadeIncrementer = 0
+)
+
+//FadeOut covers the screen with the color over duration seconds, and
+// calls done once the screen is covered.  The screen stays covered
+// until FadeIn is called.  If a fade is already running, this one starts
+// once it's done, so neither fade's done is lost
+func FadeOut(duration float64, color *Color, done func()) {
+	if color != nil {
+		fadeColor = color
+	}
+	fadeTo(1, duration, done)
+}
+
+//FadeIn uncovers the screen over duration seconds, and calls done
+// once the screen is clear
+func FadeIn(duration float64, done func()) {
+	fadeTo(0, duration, done)
+}
+
+//SetFade immediately covers the screen with the color at the passed in
+// alpha from 0 to 1, cancelling any fades in progress or waiting without
+// calling their done functions
+func SetFade(color *Color, alpha float32) {
+	if fadeTween != nil {
+		fadeTween.Stop()
+	}
+	fadeQueue = nil
+	if color != nil {
+		fadeColor = color
+	}
+	fadeAlpha = alpha
+}
+
+//Fading returns true if a fade is in progress
+func Fading() bool {
+	return fadeTween != nil && !fadeTween.Done()
+}
+
+func fadeTo(alpha float32, duration float64, done func()) {
+	start := func() {
+		fadeTween = TweenFloat(&fadeAlpha, alpha, duration, EaseInOutSine).Then(func() {
+			if done != nil {
+				done()
+			}
+			nextFade()
+		})
+	}
+	if Fading() {
+		fadeQueue = append(fadeQueue, start)
+		return
+	}
+	start()
+}
+
+//nextFade starts the next waiting fade, unless the last fade's done
+// function started one of its own
+func nextFade() {
+	if Fading() || len(fadeQueue) == 0 {
+		return
+	}
+	start := fadeQueue[0]
+	fadeQueue = fadeQueue[1:]
+	start()
+}
+
+//placeScreenFade draws the fade over the whole screen
+func placeScreenFade() {
+	if fadeAlpha <= 0 {
+		return
+	}
+	if fadeMaterial == nil {
+		loadScreenFade()
+	}
+
+	area := NewScreenArea(0, 0, screenRatio, 1, ScreenRelativeAspect)
+	area.toVertex(tempArray[:])
+	horde3d.ShowOverlays(tempArray[:], 4, fadeColor.R(), fadeColor.G(), fadeColor.B(),
+		fadeColor.A()*fadeAlpha, fadeMaterial.H3DRes, 0)
+}
+
+func loadScreenFade() {
+	fadeIncrementer++
+	name := "ScreenFadeOverlay_" + strconv.Itoa(fadeIncrementer)
+	materialData := `<Material>
+		<Shader source="shaders/overlay.shader"/>
+
+		<Sampler name="albedoMap" map="` + name + `" />
+		</Material>`
+
+	fadeTexture = NewVirtualTexture(name, 1, 1, horde3d.Formats_TEX_BGRA8, horde3d.ResFlags_NoTexMipmaps)
+	fadeMaterial = &Material{NewVirtualResource(name+".material.xml",
+		ResTypeMaterial, []byte(materialData))}
+	fadeMaterial.Load()
+	fadeMaterial.SetResParamI(horde3d.MatRes_SamplerElem, 0, horde3d.MatRes_SampTexResI,
+		int(fadeTexture.H3DRes))
+
+	white := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	white.Set(0, 0, color.White)
+	fadeTexture.SetData(white)
+	fadeTexture.Load()
+}
+
+//unloadScreenFade removes the fade's resources, they're recreated the
+// next time the fade is drawn
+func unloadScreenFade() {
+	if fadeMaterial == nil {
+		return
+	}
+	fadeMaterial.Remove()
+	fadeTexture.Remove()
+	fadeMaterial = nil
+	fadeTexture = nil
+}
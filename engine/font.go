@@ -0,0 +1,357 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gohorde/horde3d"
+	"code.google.com/p/freetype-go/freetype"
+	"code.google.com/p/freetype-go/freetype/truetype"
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	freeTypeDPI       = 72
+	glyphAtlasSize    = 256 //starting width and height of a font's glyph atlas in pixels
+	maxGlyphAtlasSize = 2048
+	glyphPadding      = 1 //empty pixels around each glyph so they don't bleed into each other
+)
+
+var (
+	parsedFonts     = make(map[string]*truetype.Font)
+	loadedFonts     = make(map[string]*Font)
+	fontIncrementer = 0
+)
+
+//Font is a truetype font at a single pixel size.  Glyphs are rasterized
+// the first time they're used into an atlas texture that's shared by
+// every Text using the same font file and size, so changing text only
+// rasterizes characters that haven't been drawn before.
+// Sizes are screen ratio based like the gui system
+type Font struct {
+	file     string
+	size     float64 //pixels
+	scale    float64 //pixels per font unit
+	font     *truetype.Font
+	context  *freetype.Context
+	glyphBuf *truetype.GlyphBuf
+	glyphs   map[rune]*glyph
+	atlas    *image.RGBA
+	texture  *Texture
+	material *Material
+	dirty    bool
+	//next open spot in the atlas
+	penX, penY, rowHeight int
+}
+
+//glyph is a character rasterized into a font's atlas.  The offset is
+// from the pen position on the baseline to the top left of the glyph
+type glyph struct {
+	index            truetype.Index
+	advance          float64
+	x, y, w, h       int
+	offsetX, offsetY int
+}
+
+//LoadFont returns the font from the passed in file at the given size
+// fonts are cached, so texts of the same font and size share one atlas
+func LoadFont(file string, size float64) (*Font, error) {
+	pixels := int(math.Max(1, math.Floor(float64(screenHeight)*size+0.5)))
+	key := file + "_" + strconv.Itoa(pixels)
+	if font, ok := loadedFonts[key]; ok {
+		return font, nil
+	}
+
+	ttf, err := parseFont(file)
+	if err != nil {
+		return nil, err
+	}
+
+	font := &Font{
+		file:     file,
+		size:     float64(pixels),
+		scale:    float64(pixels) / float64(ttf.UnitsPerEm()),
+		font:     ttf,
+		context:  freetype.NewContext(),
+		glyphBuf: truetype.NewGlyphBuf(),
+		glyphs:   make(map[rune]*glyph),
+		atlas:    image.NewRGBA(image.Rect(0, 0, glyphAtlasSize, glyphAtlasSize)),
+	}
+	font.context.SetDPI(freeTypeDPI)
+	font.context.SetFont(ttf)
+	font.context.SetFontSize(font.size)
+	font.context.SetDst(font.atlas)
+	font.context.SetSrc(image.White)
+
+	if err = font.createTexture(); err != nil {
+		return nil, err
+	}
+
+	loadedFonts[key] = font
+	return font, nil
+}
+
+func parseFont(file string) (*truetype.Font, error) {
+	if ttf, ok := parsedFonts[file]; ok {
+		return ttf, nil
+	}
+
+	fontData, err := loadEngineData(file)
+	if err != nil {
+		return nil, err
+	}
+	ttf, err := freetype.ParseFont(fontData)
+	if err != nil {
+		return nil, err
+	}
+	parsedFonts[file] = ttf
+	return ttf, nil
+}
+
+//unloadFonts removes every font's atlas, so they're rebuilt at the
+// current screen size the next time they're loaded
+func unloadFonts() {
+	for key, font := range loadedFonts {
+		font.removeTexture()
+		delete(loadedFonts, key)
+	}
+}
+
+//updateFonts uploads any glyphs added to the font atlases since the
+// last frame
+func updateFonts() {
+	for _, font := range loadedFonts {
+		if font.dirty {
+			font.texture.SetData(font.atlas)
+			font.texture.Load()
+			font.dirty = false
+		}
+	}
+}
+
+func (f *Font) createTexture() error {
+	fontIncrementer++
+	name := "FontAtlas_" + strconv.Itoa(fontIncrementer)
+	materialData := `<Material>
+		<Shader source="shaders/overlay.shader"/>
+
+		<Sampler name="albedoMap" map="` + name + `" />
+		</Material>`
+
+	bounds := f.atlas.Bounds()
+	f.texture = NewVirtualTexture(name, bounds.Dx(), bounds.Dy(), horde3d.Formats_TEX_BGRA8,
+		horde3d.ResFlags_NoTexMipmaps)
+	if f.texture == nil {
+		return errors.New("Unable to create the glyph atlas for font " + f.file)
+	}
+	f.material = &Material{NewVirtualResource(name+".material.xml",
+		ResTypeMaterial, []byte(materialData))}
+
+	f.material.Load()
+	f.material.SetResParamI(horde3d.MatRes_SamplerElem, 0, horde3d.MatRes_SampTexResI,
+		int(f.texture.H3DRes))
+	f.dirty = true
+	return nil
+}
+
+func (f *Font) removeTexture() {
+	f.material.Remove()
+	f.texture.Remove()
+}
+
+//glyph returns the rasterized glyph for the rune, adding it to the
+// atlas if it hasn't been used yet
+func (f *Font) glyph(r rune) *glyph {
+	if g, ok := f.glyphs[r]; ok {
+		return g
+	}
+
+	index := f.font.Index(r)
+	g := &glyph{
+		index:   index,
+		advance: float64(f.font.HMetric(index).AdvanceWidth) * f.scale,
+	}
+	f.glyphs[r] = g
+
+	if err := f.glyphBuf.Load(f.font, index); err != nil {
+		RaiseError(err)
+		return g
+	}
+	bounds := f.glyphBuf.B
+	x1 := int(math.Floor(float64(bounds.XMin) * f.scale))
+	x2 := int(math.Ceil(float64(bounds.XMax) * f.scale))
+	y1 := int(math.Floor(-float64(bounds.YMax) * f.scale))
+	y2 := int(math.Ceil(-float64(bounds.YMin) * f.scale))
+	g.w, g.h = x2-x1, y2-y1
+	g.offsetX, g.offsetY = x1, y1
+
+	if g.w <= 0 || g.h <= 0 || !f.reserve(g) {
+		//nothing to draw, i.e. a space
+		g.w, g.h = 0, 0
+		return g
+	}
+
+	f.context.SetClip(image.Rect(g.x, g.y, g.x+g.w, g.y+g.h))
+	if _, err := f.context.DrawString(string(r), freetype.Pt(g.x-g.offsetX, g.y-g.offsetY)); err != nil {
+		RaiseError(err)
+	}
+	f.dirty = true
+	return g
+}
+
+//reserve finds room in the atlas for the glyph, growing the atlas if
+// it's full
+func (f *Font) reserve(g *glyph) bool {
+	w, h := g.w+glyphPadding*2, g.h+glyphPadding*2
+
+	if f.penX+w > f.atlas.Bounds().Dx() {
+		f.penX = 0
+		f.penY += f.rowHeight
+		f.rowHeight = 0
+	}
+
+	for w > f.atlas.Bounds().Dx() || f.penY+h > f.atlas.Bounds().Dy() {
+		width, height := f.atlas.Bounds().Dx(), f.atlas.Bounds().Dy()
+		if w > width {
+			width *= 2
+		} else {
+			height *= 2
+		}
+		if !f.growAtlas(width, height) {
+			RaiseError(errors.New("Glyph atlas for font " + f.file + " is full."))
+			return false
+		}
+	}
+
+	g.x, g.y = f.penX+glyphPadding, f.penY+glyphPadding
+	f.penX += w
+	if h > f.rowHeight {
+		f.rowHeight = h
+	}
+	return true
+}
+
+//growAtlas copies the atlas into a larger texture.  Existing glyphs keep
+// their pixel positions
+func (f *Font) growAtlas(width, height int) bool {
+	if width > maxGlyphAtlasSize || height > maxGlyphAtlasSize {
+		return false
+	}
+
+	atlas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(atlas, f.atlas.Bounds(), f.atlas, image.ZP, draw.Src)
+	f.atlas = atlas
+	f.context.SetDst(atlas)
+
+	f.removeTexture()
+	if err := f.createTexture(); err != nil {
+		RaiseError(err)
+		return false
+	}
+	return true
+}
+
+//kerning returns the adjustment in pixels between two glyphs
+func (f *Font) kerning(prev, next *glyph) float64 {
+	return float64(f.font.Kerning(prev.index, next.index)) * f.scale
+}
+
+//measure returns the width of the string in pixels
+func (f *Font) measure(text string) float64 {
+	var width float64
+	var prev *glyph
+	for _, r := range text {
+		g := f.glyph(r)
+		if prev != nil {
+			width += f.kerning(prev, g)
+		}
+		width += g.advance
+		prev = g
+	}
+	return width
+}
+
+func (f *Font) ascent() float64  { return float64(f.font.Bounds().YMax) * f.scale }
+func (f *Font) descent() float64 { return -float64(f.font.Bounds().YMin) * f.scale }
+
+//wrap splits the line into lines no wider than width pixels, breaking
+// between words where possible
+func (f *Font) wrap(line string, width float64) []string {
+	if width <= 0 || f.measure(line) <= width {
+		return []string{line}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range strings.Split(line, " ") {
+		next := word
+		if current != "" {
+			next = current + " " + word
+		}
+		if f.measure(next) <= width {
+			current = next
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+
+		//break up words that don't fit on a line by themselves
+		current = ""
+		for _, r := range word {
+			if current != "" && f.measure(current+string(r)) > width {
+				lines = append(lines, current)
+				current = ""
+			}
+			current += string(r)
+		}
+	}
+	return append(lines, current)
+}
+
+//File is the font file the font was loaded from
+func (f *Font) File() string { return f.file }
+
+//Ascent is the height above the baseline of the tallest glyph in the
+// font in screen units
+func (f *Font) Ascent() float32 { return toScreenUnits(f.ascent()) }
+
+//Descent is the depth below the baseline of the lowest glyph in the
+// font in screen units
+func (f *Font) Descent() float32 { return toScreenUnits(f.descent()) }
+
+//LineHeight is the distance between the baselines of single spaced
+// lines in screen units
+func (f *Font) LineHeight() float32 { return toScreenUnits(f.size) }
+
+//Measure returns the width of the string in screen units
+func (f *Font) Measure(text string) float32 { return toScreenUnits(f.measure(text)) }
+
+//MeasureText returns the width and height of the lines of text in screen
+// units, as it would be drawn single spaced with the passed in font file and size
+func MeasureText(text []string, fontFile string, size float64) (width, height float32, err error) {
+	font, err := LoadFont(fontFile, size)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range text {
+		width = max32(width, font.Measure(text[i]))
+	}
+	if len(text) > 0 {
+		height = toScreenUnits(font.ascent() + font.descent() + font.size*float64(len(text)-1))
+	}
+	return width, height, nil
+}
+
+//toScreenUnits converts pixels to screen units, which are relative to
+// the screen height on both axes
+func toScreenUnits(pixels float64) float32 {
+	return float32(pixels / float64(screenHeight))
+}
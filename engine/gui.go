@@ -7,6 +7,7 @@ package engine
 import (
 	"bitbucket.org/tshannon/gohorde/horde3d"
 	"github.com/jteeuwen/glfw"
+	"time"
 )
 
 //Used for both menus and HUDs
@@ -25,27 +26,46 @@ var screenHeight int
 var screenWidth int
 var tempArray [16]float32 //Rectangles only for now
 var activeGuis []*Gui
+var clipStack []*ScreenArea
+
+//guis that have been unloaded, but are still drawn until their transition ends
+var closingGuis []*Gui
+
+//alpha and offset of the gui currently being drawn, applied to every overlay
+var guiAlpha float32 = 1
+var guiOffsetX, guiOffsetY float32
 
 func initGui() {
-	glfw.SetCharCallback(charCollector)
 	activeGuis = make([]*Gui, 0, 5)
 }
 
 //LoadGui pushes a gui onto a stack of guis, only top most in the stack
-// is used for interaction
-func LoadGui(gui *Gui) {
+// is used for interaction.  An optional transition animates the gui in
+func LoadGui(gui *Gui, transition ...*Transition) {
+	removeClosingGui(gui)
 	activeGuis = append([]*Gui{gui}, activeGuis...)
 	gui.load()
+	gui.transitionIn(optionalTransition(transition))
 }
 
-//UnloadGui pops a gui off the stack of guis
-func UnloadGui() {
+//UnloadGui pops a gui off the stack of guis.  An optional transition
+// animates the gui out.  It no longer receives input while it does
+func UnloadGui(transition ...*Transition) {
 	if len(activeGuis) == 0 {
 		return
 	}
 
 	gui := activeGuis[0]
-	gui.unload()
+	if t := optionalTransition(transition); t != nil {
+		gui.release()
+		closingGuis = append(closingGuis, gui)
+		gui.transitionOut(t, func() {
+			removeClosingGui(gui)
+			gui.unloadWidgets()
+		})
+	} else {
+		gui.unload()
+	}
 
 	activeGuis = activeGuis[1:]
 	if len(activeGuis) != 0 {
@@ -58,23 +78,52 @@ func UnloadGui() {
 // the engine and inputs back to normal operation
 func UnloadAllGuis() {
 	for i := range activeGuis {
+		activeGuis[i].stopTransition()
 		activeGuis[i].unload()
 	}
 	activeGuis = activeGuis[0:0]
+
+	for i := range closingGuis {
+		closingGuis[i].stopTransition()
+		closingGuis[i].unloadWidgets()
+	}
+	closingGuis = closingGuis[0:0]
+}
+
+func removeClosingGui(gui *Gui) {
+	for i := range closingGuis {
+		if closingGuis[i] == gui {
+			gui.stopTransition()
+			closingGuis = append(closingGuis[:i], closingGuis[i+1:]...)
+			return
+		}
+	}
 }
 
 func updateGui() {
 	horde3d.ClearOverlays()
-	for i := range activeGuis {
-		if i == 0 {
-			activeGuis[i].handleInput()
-		}
-		if len(activeGuis) > 0 {
+	updateTweens()
+	if len(activeGuis) > 0 {
+		activeGuis[0].handleInput()
+	}
+
+	//huds are drawn first, then the gui stack from the bottom up
+	// so the top gui is drawn over everything else
+	updateHuds()
+	for i := len(activeGuis) - 1; i >= 0; i-- {
+		//widgets can unload guis as they update
+		if i < len(activeGuis) && !activeGuis[i].Hidden {
 			activeGuis[i].update()
 		}
 	}
+	for i := range closingGuis {
+		closingGuis[i].update()
+	}
+	placeScreenFade()
 
 	updateDebugPrint()
+	updateGuiFiles()
+	updateFonts()
 }
 
 func charCollector(key, state int) {
@@ -89,6 +138,100 @@ type CharCollector func(key int)
 
 var gCharCollector CharCollector
 
+//SetCharCollector changes the function that receives typed characters
+// pass nil to stop collecting
+func SetCharCollector(collector CharCollector) {
+	gCharCollector = collector
+}
+
+//PushClip restricts all overlays placed after it to the passed in area
+// until PopClip is called.  Clip areas are nested, so overlays are clipped
+// to the intersection of every pushed area
+func PushClip(area *ScreenArea) {
+	clip := NewScreenArea(area.X()+guiOffsetX, area.Position.Y+guiOffsetY, area.X2()-area.X(), area.Height,
+		ScreenRelativeAspect)
+	if len(clipStack) > 0 {
+		outer := clipStack[len(clipStack)-1]
+		x1 := max32(clip.Position.X, outer.Position.X)
+		y1 := max32(clip.Position.Y, outer.Position.Y)
+		x2 := min32(clip.Position.X+clip.Width, outer.Position.X+outer.Width)
+		y2 := min32(clip.Position.Y+clip.Height, outer.Position.Y+outer.Height)
+		clip.Position.X, clip.Position.Y = x1, y1
+		clip.Width, clip.Height = max32(x2-x1, 0), max32(y2-y1, 0)
+	}
+	clipStack = append(clipStack, clip)
+}
+
+//PopClip removes the last area added with PushClip
+func PopClip() {
+	if len(clipStack) > 0 {
+		clipStack = clipStack[:len(clipStack)-1]
+	}
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+//placeVertex moves the rectangle verts by the offset of the gui being
+// drawn, then clips them.  Returns false if nothing is left to draw
+func placeVertex(verts []float32) bool {
+	for i := 0; i < 16; i += 4 {
+		verts[i] += guiOffsetX
+		verts[i+1] += guiOffsetY
+	}
+	return clipVertex(verts)
+}
+
+//clipVertex clips the rectangle verts from toVertex against the current
+// clip area, adjusting texture coordinates to match.  Returns false if
+// nothing is left to draw
+func clipVertex(verts []float32) bool {
+	if len(clipStack) == 0 {
+		return true
+	}
+	clip := clipStack[len(clipStack)-1]
+
+	x1, y1 := verts[0], verts[1]
+	x2, y2 := verts[8], verts[9]
+	if x2 <= x1 || y2 <= y1 {
+		return false
+	}
+
+	cx1 := max32(x1, clip.Position.X)
+	cy1 := max32(y1, clip.Position.Y)
+	cx2 := min32(x2, clip.Position.X+clip.Width)
+	cy2 := min32(y2, clip.Position.Y+clip.Height)
+	if cx1 >= cx2 || cy1 >= cy2 {
+		return false
+	}
+
+	//interpolate the original texture coordinates, which may only
+	// cover part of a texture, i.e. a glyph in a font atlas
+	uLeft, uRight := verts[2], verts[10]
+	vTop, vBottom := verts[3], verts[11]
+	u1 := uLeft + (uRight-uLeft)*((cx1-x1)/(x2-x1))
+	u2 := uLeft + (uRight-uLeft)*((cx2-x1)/(x2-x1))
+	v1 := vTop + (vBottom-vTop)*((cy1-y1)/(y2-y1))
+	v2 := vTop + (vBottom-vTop)*((cy2-y1)/(y2-y1))
+
+	verts[0], verts[1], verts[2], verts[3] = cx1, cy1, u1, v1
+	verts[4], verts[5], verts[6], verts[7] = cx1, cy2, u1, v2
+	verts[8], verts[9], verts[10], verts[11] = cx2, cy2, u2, v2
+	verts[12], verts[13], verts[14], verts[15] = cx2, cy1, u2, v1
+	return true
+}
+
 //toVertex returns the actual position on the screen from the interpreted
 // relative position
 func (s *ScreenArea) toVertex(result []float32) {
@@ -155,6 +298,13 @@ func (s *ScreenArea) X2() float32 {
 	return 0
 }
 
+//Contains returns true if the passed in screen aspect relative
+// position is inside the area
+func (s *ScreenArea) Contains(x, y float32) bool {
+	return x >= s.X() && x <= s.X2() &&
+		y >= s.Position.Y && y <= s.Position.Y+s.Height
+}
+
 //PixelHeight is the height in actual pixels as relating to the current
 // screen resolution
 func (s *ScreenArea) PixelHeight() int {
@@ -236,14 +386,12 @@ func NewScreenPosition(X, Y float32, relativeTo int) *ScreenPosition {
 }
 
 //BitmapText is text drawn on the screen using a bitmap based fonts
-//  Can be faster for small sets of text vs freetype raster
-//  Doesn't require regerating and reloading the resource
+//  Doesn't require loading a truetype font
 //  Limited by horde's overlay limit.
 //  Recommended for use in small quick text changes (debug messages)
-//  For large font sizes, or large amounts of text use the
-//  Text type which uses a freetype rasterizer to genrate the text
-//  and only ever uses one resource, but any changes made to that
-//  text need to be re-rasterized
+//  For large font sizes, alignment or wrapping use the
+//  Text type which uses freetype to rasterize each glyph once
+//  into an atlas shared by all text of the same font and size
 type BitmapText struct {
 	Text         string
 	Position     *ScreenPosition
@@ -283,8 +431,11 @@ func (t *BitmapText) Width() float32 {
 
 func (o *Overlay) Place() {
 	o.Dimensions.toVertex(tempArray[:])
+	if !placeVertex(tempArray[:]) {
+		return
+	}
 	horde3d.ShowOverlays(tempArray[:], 4, o.Color.R(), o.Color.G(),
-		o.Color.B(), o.Color.A(), o.Material.H3DRes, 0)
+		o.Color.B(), o.Color.A()*guiAlpha, o.Material.H3DRes, 0)
 }
 
 //Widget is a collection of Overlays
@@ -298,11 +449,33 @@ type Widget interface {
 	Unload()
 }
 
+//FocusWidget is a widget that changes when it gains or loses focus
+type FocusWidget interface {
+	Widget
+	SetFocused(bool)
+}
+
+//KeyWidget is a widget that receives key presses while it has focus
+// key is the glfw key code, state is StatePressed or StateReleased
+// Returns true if the widget used the key, otherwise the key is used
+// for navigating between widgets
+type KeyWidget interface {
+	Widget
+	Key(key, state int) bool
+}
+
+//CharWidget is a widget that collects typed characters while it has focus
+type CharWidget interface {
+	Widget
+	CollectChar(key int)
+}
+
 //Gui is a collection of Widgets
 type Gui struct {
 	Widgets       []Widget
 	UseMouse      bool
 	HaltInput     bool
+	Hidden        bool
 	CharCollect   CharCollector
 	prevTime      float64
 	prevWheelPos  int
@@ -310,14 +483,84 @@ type Gui struct {
 	inputs        *inputGroup
 	prevMousePosX int
 	prevMousePosY int
+	focus         Widget
+	file          string
+	modTime       time.Time
+	nav           *navState
+	alpha         float32
+	offsetX       float32
+	offsetY       float32
+	transition    *Tween
+	layer         int
 }
 
 func NewGui() *Gui {
 	gui := new(Gui)
-	gui.inputs = newInputGroup()
+	gui.alpha = 1
+	gui.inputs = gui.newGuiInputGroup()
 	return gui
 }
 
+//Alpha is the opacity from 0 to 1 applied to everything in the gui
+func (g *Gui) Alpha() float32 { return g.alpha }
+func (g *Gui) SetAlpha(alpha float32) {
+	g.alpha = alpha
+}
+
+//Offset is how far in screen units the whole gui is moved from
+// where its widgets are positioned
+func (g *Gui) Offset() (x, y float32) { return g.offsetX, g.offsetY }
+func (g *Gui) SetOffset(x, y float32) {
+	g.offsetX, g.offsetY = x, y
+}
+
+//TweenAlpha animates the opacity of the whole gui
+func (g *Gui) TweenAlpha(to float32, duration float64, ease EaseFunc) *Tween {
+	return TweenFloat(&g.alpha, to, duration, ease)
+}
+
+//TweenOffset animates the offset of the whole gui
+func (g *Gui) TweenOffset(x, y float32, duration float64, ease EaseFunc) *Tween {
+	fromX, fromY := g.offsetX, g.offsetY
+	return NewTween(duration, ease, func(progress float32) {
+		g.offsetX = lerp32(fromX, x, progress)
+		g.offsetY = lerp32(fromY, y, progress)
+	})
+}
+
+//Focus returns the widget that currently receives keyboard input
+func (g *Gui) Focus() Widget {
+	return g.focus
+}
+
+//SetFocus makes the passed in widget receive keyboard input
+// pass nil to clear the focus
+func (g *Gui) SetFocus(widget Widget) {
+	if g.focus == widget {
+		return
+	}
+	if focused, ok := g.focus.(FocusWidget); ok {
+		focused.SetFocused(false)
+	}
+	g.focus = widget
+	if focused, ok := widget.(FocusWidget); ok {
+		focused.SetFocused(true)
+	}
+	if len(activeGuis) != 0 && activeGuis[0] == g {
+		g.setCharCollector()
+	}
+}
+
+//setCharCollector sends typed characters to the focused widget if it
+// collects them, otherwise to the gui's CharCollect
+func (g *Gui) setCharCollector() {
+	if charWidget, ok := g.focus.(CharWidget); ok {
+		gCharCollector = charWidget.CollectChar
+	} else {
+		gCharCollector = g.CharCollect
+	}
+}
+
 func (g *Gui) Bind(function InputHandler, input string) {
 	g.inputs.bind(function, input, input)
 }
@@ -326,6 +569,16 @@ func (g *Gui) ElapsedTime() float64 {
 	return (glfw.Time() - g.prevTime)
 }
 
+//Widget returns the widget with the given name
+func (g *Gui) Widget(name string) (Widget, bool) {
+	for i := range g.Widgets {
+		if g.Widgets[i].Name() == name {
+			return g.Widgets[i], true
+		}
+	}
+	return nil, false
+}
+
 //AddWidget adds a widget to the last / top location
 // of the gui
 func (g *Gui) AddWidget(widget Widget) {
@@ -336,6 +589,9 @@ func (g *Gui) AddWidget(widget Widget) {
 func (g *Gui) RemoveWidget(name string) {
 	for i := 0; i < len(g.Widgets); i++ {
 		if g.Widgets[i].Name() == name {
+			if g.focus == g.Widgets[i] {
+				g.SetFocus(nil)
+			}
 			if len(g.Widgets) > 1 {
 				g.Widgets = append(g.Widgets[:i], g.Widgets[i+1:]...)
 			} else {
@@ -347,9 +603,13 @@ func (g *Gui) RemoveWidget(name string) {
 
 func (g *Gui) load() {
 	//TODO; Might be overkill
-	err := LoadAllResources()
-	if err != nil {
-		RaiseError(err)
+	// Skipped while resources are loading in the background, so showing
+	// a loading screen doesn't load everything at once
+	if !Loading() {
+		err := LoadAllResources()
+		if err != nil {
+			RaiseError(err)
+		}
 	}
 
 	if g.HaltInput {
@@ -365,15 +625,26 @@ func (g *Gui) load() {
 	} else {
 		glfw.Disable(glfw.MouseCursor)
 	}
-	gCharCollector = g.CharCollect
+	g.setCharCollector()
+	watchGuiFile(g)
 }
 
 func (g *Gui) unload() {
+	g.release()
+	g.unloadWidgets()
+}
+
+//release gives up the gui's hold on input and the mouse
+func (g *Gui) release() {
+	unwatchGuiFile(g)
 	glfw.Disable(glfw.MouseCursor)
 	glfw.SetMousePos(g.prevMousePosX, g.prevMousePosY)
 	glfw.PollEvents()
 	unloadInputGroup()
 	gCharCollector = nil
+}
+
+func (g *Gui) unloadWidgets() {
 	for i := range g.Widgets {
 		g.Widgets[i].Unload()
 	}
@@ -401,6 +672,7 @@ func (g *Gui) handleInput() {
 			widget.Hover()
 			for i := range g.mousePress {
 				if g.mouseClick(i) {
+					g.SetFocus(widget)
 					widget.Click(i)
 				}
 			}
@@ -410,16 +682,23 @@ func (g *Gui) handleInput() {
 				widget.Scroll(g.prevWheelPos - delta)
 			}
 		}
+		g.prevWheelPos = glfw.MouseWheel()
 	}
 
 }
 
 func (g *Gui) update() {
+	clipStack = clipStack[0:0]
+	guiAlpha = g.alpha
+	guiOffsetX, guiOffsetY = g.offsetX, g.offsetY
 
 	for i := range g.Widgets {
 		g.Widgets[i].Update()
 	}
 	g.prevTime = glfw.Time()
+
+	guiAlpha = 1
+	guiOffsetX, guiOffsetY = 0, 0
 }
 
 func (g *Gui) WidgetUnderMouse() (Widget, bool) {
@@ -430,8 +709,7 @@ func (g *Gui) WidgetUnderMouse() (Widget, bool) {
 	for i := len(g.Widgets) - 1; i >= 0; i-- {
 		dimensions = g.Widgets[i].MouseArea()
 		x, y = g.MousePos(ScreenRelativeAspect)
-		if x >= dimensions.X() && x <= dimensions.X2() &&
-			y >= dimensions.Position.Y && y <= dimensions.Position.Y+dimensions.Height {
+		if dimensions.Contains(x, y) {
 			return g.Widgets[i], true
 
 		}
@@ -441,9 +719,14 @@ func (g *Gui) WidgetUnderMouse() (Widget, bool) {
 }
 
 func updateGuiScreenSize(w, h int) {
+	resized := screenHeight != 0 && screenHeight != h
 	screenHeight = h
 	screenWidth = w
 	screenRatio = float32(w) / float32(h)
+	if resized {
+		//font sizes are relative to the screen height
+		resetAllText()
+	}
 }
 
 func ScreenRatio() float32 {
@@ -451,6 +734,12 @@ func ScreenRatio() float32 {
 }
 
 func (g *Gui) MousePos(relative int) (x, y float32) {
+	return ScreenMousePos(relative)
+}
+
+//ScreenMousePos returns the mouse position in the same units as
+// a ScreenPosition with the given relative positioning
+func ScreenMousePos(relative int) (x, y float32) {
 	//Return position according to widget ratio positioning
 	//  0.0 - 1.0
 	gX, gY := glfw.MousePos()
@@ -24,6 +24,7 @@ type Button struct {
 	TextHover      *engine.Text
 	TextClick      *engine.Text
 	hover          bool
+	focused        bool
 	showBackground bool
 	ClickEvent     func(sender string)
 }
@@ -36,8 +37,6 @@ func MakeButton(name, text string, textSize float64, dimensions *engine.ScreenAr
 	textColor := engine.NewColor(255, 255, 255, 0)
 
 	textSlice := []string{text}
-	//TODO: Determine text position
-	// Auto size button to hold text, center text vertically
 	textPosition := dimensions
 
 	button := &Button{
@@ -51,6 +50,7 @@ func MakeButton(name, text string, textSize float64, dimensions *engine.ScreenAr
 		TextClick:              engine.NewText(textSlice, defaultFont, textSize, textColor, textPosition),
 	}
 	button.dimensions = button.BackgroundOverlay.Dimensions
+	button.setTextAlign(engine.AlignCenter)
 	return button
 
 }
@@ -63,6 +63,43 @@ func (b *Button) ShowBackground(value bool) {
 		b.dimensions = b.Text.Area()
 	}
 	b.showBackground = value
+
+	//text is centered on backgrounds, and left aligned without one
+	if value {
+		b.setTextAlign(engine.AlignCenter)
+	} else {
+		b.setTextAlign(engine.AlignLeft)
+	}
+}
+
+func (b *Button) setTextAlign(align int) {
+	b.Text.SetAlign(align, engine.AlignMiddle)
+	b.TextHover.SetAlign(align, engine.AlignMiddle)
+	b.TextClick.SetAlign(align, engine.AlignMiddle)
+}
+
+//SizeToText resizes the button to fit its text plus the passed in
+// padding in screen units on each side
+func (b *Button) SizeToText(padding float32) {
+	bounds := b.Text.Bounds()
+	area := b.BackgroundOverlay.Dimensions
+	area.Width = bounds.Width + (padding * 2)
+	area.Height = bounds.Height + (padding * 2)
+}
+
+//SetText changes the text of the button in all of its states
+func (b *Button) SetText(text string) {
+	b.Text.SetText(text)
+	b.TextHover.SetText(text)
+	b.TextClick.SetText(text)
+}
+
+//SetTextID sets the button's text to the localized string for the id
+// in all of its states
+func (b *Button) SetTextID(id string, args ...interface{}) {
+	b.Text.SetTextID(id, args...)
+	b.TextHover.SetTextID(id, args...)
+	b.TextClick.SetTextID(id, args...)
 }
 
 func (b *Button) Name() string {
@@ -76,8 +113,13 @@ func (b *Button) Hover() {
 	b.hover = true
 }
 
+//SetFocused shows the button as hovered while it has keyboard focus
+func (b *Button) SetFocused(value bool) {
+	b.focused = value
+}
+
 func (b *Button) Update() {
-	if b.hover {
+	if b.hover || b.focused {
 		if b.showBackground {
 			b.BackgroundHoverOverlay.Place()
 		}
@@ -103,7 +145,9 @@ func (b *Button) Click(button int) {
 		if len(b.Text.Text()) != 0 {
 			b.TextClick.Place()
 		}
-		b.ClickEvent(b.name)
+		if b.ClickEvent != nil {
+			b.ClickEvent(b.name)
+		}
 	}
 }
 
@@ -0,0 +1,128 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+const (
+	checkboxInset      = .25 //percentage of the box's size the check is inset by
+	checkboxLabelSpace = 1.25
+)
+
+//Checkbox is a box that toggles between checked and unchecked when clicked
+// with a text label to the right of it
+type Checkbox struct {
+	name        string
+	dimensions  *engine.ScreenArea
+	Box         *engine.Overlay
+	BoxHover    *engine.Overlay
+	Check       *engine.Overlay
+	Label       *engine.Text
+	checked     bool
+	hover       bool
+	focused     bool
+	ChangeEvent func(sender string, checked bool)
+}
+
+//MakeCheckbox returns a checkbox with the default background and colors
+// the box is sized to the height of the dimensions, and the label fills the rest
+func MakeCheckbox(name, text string, textSize float64, checked bool,
+	dimensions *engine.ScreenArea) *Checkbox {
+	boxArea := newArea()
+	labelArea := engine.NewScreenArea(0, 0, dimensions.Width-(dimensions.Height*checkboxLabelSpace),
+		dimensions.Height, engine.ScreenRelativeAspect)
+
+	checkbox := &Checkbox{
+		name:       name,
+		dimensions: dimensions,
+		Box:        engine.NewOverlay(defaultBackground, defaultColor(), boxArea),
+		BoxHover:   engine.NewOverlay(defaultBackground, hoverColor(), boxArea),
+		Check:      engine.NewOverlay(defaultBackground, accentColor(), newArea()),
+		Label:      engine.NewText([]string{text}, defaultFont, textSize, textColor(), labelArea),
+		checked:    checked,
+	}
+	checkbox.Label.SetAlign(engine.AlignLeft, engine.AlignMiddle)
+	checkbox.layout()
+	return checkbox
+}
+
+func (c *Checkbox) Checked() bool { return c.checked }
+
+//SetChecked sets the checkbox's state and calls the ChangeEvent if it changed
+func (c *Checkbox) SetChecked(value bool) {
+	if value == c.checked {
+		return
+	}
+	c.checked = value
+	if c.ChangeEvent != nil {
+		c.ChangeEvent(c.name, c.checked)
+	}
+}
+
+func (c *Checkbox) layout() {
+	x := c.dimensions.X()
+	y := c.dimensions.Position.Y
+	size := c.dimensions.Height
+	inset := size * checkboxInset
+
+	setArea(c.Box.Dimensions, x, y, size, size)
+	setArea(c.Check.Dimensions, x+inset, y+inset, size-(inset*2), size-(inset*2))
+
+	label := c.Label.Area()
+	label.Position.X = x + (size * checkboxLabelSpace)
+	label.Position.Y = y
+	label.Position.RelativeTo = engine.ScreenRelativeAspect
+}
+
+func (c *Checkbox) Name() string { return c.name }
+func (c *Checkbox) MouseArea() *engine.ScreenArea {
+	return c.dimensions
+}
+
+func (c *Checkbox) Hover() {
+	c.hover = true
+}
+
+func (c *Checkbox) Update() {
+	c.layout()
+	if c.hover || c.focused {
+		c.BoxHover.Place()
+	} else {
+		c.Box.Place()
+	}
+	if c.checked {
+		c.Check.Place()
+	}
+	if len(c.Label.Text()) != 0 {
+		c.Label.Place()
+	}
+	c.hover = false
+}
+
+func (c *Checkbox) Click(button int) {
+	if button == 0 {
+		c.SetChecked(!c.checked)
+	}
+}
+
+func (c *Checkbox) Key(key, state int) bool {
+	if key != keyEnter && key != keySpace {
+		return false
+	}
+	if state == engine.StatePressed {
+		c.SetChecked(!c.checked)
+	}
+	return true
+}
+
+func (c *Checkbox) SetFocused(value bool) { c.focused = value }
+
+func (c *Checkbox) Scroll(delta int) { return }
+
+func (c *Checkbox) Unload() {
+	c.Label.Unload()
+}
@@ -0,0 +1,228 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+//Dropdown shows the selected item, and when clicked opens a list
+// of all of the items below it to choose from
+type Dropdown struct {
+	name           string
+	dimensions     *engine.ScreenArea
+	openArea       *engine.ScreenArea
+	Background     *engine.Overlay
+	HoverOverlay   *engine.Overlay
+	ItemBackground *engine.Overlay
+	Text           *engine.Text
+	items          []string
+	itemTexts      []*engine.Text
+	selected       int
+	hoverItem      int
+	open           bool
+	hover          bool
+	focused        bool
+	SelectEvent    func(sender string, index int)
+}
+
+//MakeDropdown returns a dropdown with the default background and colors
+// each item in the open list is the same height as the dropdown
+func MakeDropdown(name string, items []string, textSize float64, dimensions *engine.ScreenArea) *Dropdown {
+	dropdown := &Dropdown{
+		name:           name,
+		dimensions:     dimensions,
+		openArea:       newArea(),
+		Background:     engine.NewOverlay(defaultBackground, defaultColor(), dimensions),
+		HoverOverlay:   engine.NewOverlay(defaultBackground, hoverColor(), newArea()),
+		ItemBackground: engine.NewOverlay(defaultBackground, defaultColor(), newArea()),
+		items:          items,
+		itemTexts:      make([]*engine.Text, len(items)),
+		hoverItem:      -1,
+	}
+
+	var selected string
+	if len(items) > 0 {
+		selected = items[0]
+	}
+	dropdown.Text = engine.NewText([]string{selected}, defaultFont, textSize, textColor(), dimensions)
+	dropdown.Text.SetAlign(engine.AlignLeft, engine.AlignMiddle)
+
+	for i := range items {
+		area := engine.NewScreenArea(0, 0, dimensions.Width, dimensions.Height, engine.ScreenRelativeAspect)
+		dropdown.itemTexts[i] = engine.NewText([]string{items[i]}, defaultFont, textSize, textColor(), area)
+		dropdown.itemTexts[i].SetAlign(engine.AlignLeft, engine.AlignMiddle)
+	}
+	dropdown.layout()
+	return dropdown
+}
+
+func (d *Dropdown) Items() []string { return d.items }
+func (d *Dropdown) Selected() int   { return d.selected }
+
+func (d *Dropdown) SelectedItem() string {
+	if d.selected < 0 || d.selected >= len(d.items) {
+		return ""
+	}
+	return d.items[d.selected]
+}
+
+//Select sets the selected item and calls the SelectEvent if it changed
+func (d *Dropdown) Select(index int) {
+	if index < 0 || index >= len(d.items) || index == d.selected {
+		return
+	}
+	d.selected = index
+	d.Text.SetText(d.items[index])
+	if d.SelectEvent != nil {
+		d.SelectEvent(d.name, index)
+	}
+}
+
+func (d *Dropdown) IsOpen() bool { return d.open }
+
+func (d *Dropdown) layout() {
+	x := d.dimensions.X()
+	y := d.dimensions.Position.Y
+	width := d.dimensions.X2() - x
+	height := d.dimensions.Height
+
+	setArea(d.openArea, x, y, width, height*float32(len(d.items)+1))
+	setArea(d.ItemBackground.Dimensions, x, y+height, width, height*float32(len(d.items)))
+
+	for i := range d.itemTexts {
+		area := d.itemTexts[i].Area()
+		area.Position.X = x
+		area.Position.Y = y + height*float32(i+1)
+		area.Position.RelativeTo = engine.ScreenRelativeAspect
+	}
+}
+
+//itemUnderMouse returns the index of the open item under the mouse or -1
+func (d *Dropdown) itemUnderMouse() int {
+	x, y := engine.ScreenMousePos(engine.ScreenRelativeAspect)
+	for i := range d.itemTexts {
+		if d.itemTexts[i].Area().Contains(x, y) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *Dropdown) Name() string { return d.name }
+
+func (d *Dropdown) MouseArea() *engine.ScreenArea {
+	if d.open {
+		return d.openArea
+	}
+	return d.dimensions
+}
+
+func (d *Dropdown) Hover() {
+	d.hover = true
+	if d.open {
+		d.hoverItem = d.itemUnderMouse()
+	}
+}
+
+func (d *Dropdown) Update() {
+	d.layout()
+	d.Background.Place()
+	if d.hover || d.focused {
+		setArea(d.HoverOverlay.Dimensions, d.dimensions.X(), d.dimensions.Position.Y,
+			d.dimensions.X2()-d.dimensions.X(), d.dimensions.Height)
+		d.HoverOverlay.Place()
+	}
+	if len(d.items) != 0 {
+		d.Text.Place()
+	}
+
+	if d.open {
+		d.ItemBackground.Place()
+		if d.hoverItem >= 0 {
+			area := d.itemTexts[d.hoverItem].Area()
+			setArea(d.HoverOverlay.Dimensions, area.X(), area.Position.Y, area.Width, area.Height)
+			d.HoverOverlay.Place()
+		}
+		for i := range d.itemTexts {
+			d.itemTexts[i].Place()
+		}
+	}
+	d.hover = false
+}
+
+func (d *Dropdown) Click(button int) {
+	if button != 0 {
+		return
+	}
+	if !d.open {
+		d.open = true
+		d.hoverItem = d.selected
+		return
+	}
+
+	if item := d.itemUnderMouse(); item >= 0 {
+		d.Select(item)
+	}
+	d.open = false
+}
+
+func (d *Dropdown) Scroll(delta int) {
+	d.Select(d.selected + delta)
+}
+
+//Key steps through the items with left and right while closed, and up
+// and down while open
+func (d *Dropdown) Key(key, state int) bool {
+	switch key {
+	case keyUp, keyDown:
+		if !d.open {
+			return false
+		}
+	case keyLeft, keyRight, keyEnter, keySpace:
+	default:
+		return false
+	}
+	if state != engine.StatePressed {
+		return true
+	}
+	switch key {
+	case keyUp:
+		if d.hoverItem > 0 {
+			d.hoverItem--
+		}
+	case keyDown:
+		if d.hoverItem < len(d.items)-1 {
+			d.hoverItem++
+		}
+	case keyLeft:
+		d.Select(d.selected - 1)
+	case keyRight:
+		d.Select(d.selected + 1)
+	case keyEnter, keySpace:
+		if d.open && d.hoverItem >= 0 {
+			d.Select(d.hoverItem)
+			d.open = false
+		} else {
+			d.open = true
+			d.hoverItem = d.selected
+		}
+	}
+	return true
+}
+
+func (d *Dropdown) SetFocused(value bool) {
+	d.focused = value
+	if !value {
+		d.open = false
+	}
+}
+
+func (d *Dropdown) Unload() {
+	d.Text.Unload()
+	for i := range d.itemTexts {
+		d.itemTexts[i].Unload()
+	}
+}
@@ -15,6 +15,7 @@ type Image struct {
 
 func MakeImage(name, imagePath string, dimensions *engine.ScreenArea) *Image {
 	newImage := new(Image)
+	newImage.name = name
 	newImage.Overlay = engine.NewOverlay(imagePath, engine.NewColor(255, 255, 255, 255), dimensions)
 	return newImage
 }
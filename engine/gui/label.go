@@ -0,0 +1,67 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+	"strings"
+)
+
+//Label is text that doesn't respond to input, i.e. for huds.
+// Setting the label to the text it already shows does nothing, so
+// it can be set every frame
+type Label struct {
+	name  string
+	Text  *engine.Text
+	value string
+}
+
+//MakeLabel returns a label with the default font and color.  Line breaks
+// in the text split it into separate lines
+func MakeLabel(name, text string, textSize float64, dimensions *engine.ScreenArea) *Label {
+	label := &Label{
+		name:  name,
+		Text:  engine.NewText(strings.Split(text, "\n"), defaultFont, textSize, textColor(), dimensions),
+		value: text,
+	}
+	label.Text.SetAlign(engine.AlignLeft, engine.AlignMiddle)
+	return label
+}
+
+func (l *Label) Value() string { return l.value }
+
+//SetText changes the label's text if it's different from the current text
+func (l *Label) SetText(text string) {
+	if text == l.value && l.Text.TextID() == "" {
+		return
+	}
+	l.value = text
+	l.Text.SetText(strings.Split(text, "\n")...)
+}
+
+//SetTextID sets the label to the localized string for the id
+func (l *Label) SetTextID(id string, args ...interface{}) {
+	l.Text.SetTextID(id, args...)
+	l.value = strings.Join(l.Text.Text(), "\n")
+}
+
+func (l *Label) Name() string { return l.name }
+func (l *Label) MouseArea() *engine.ScreenArea {
+	return l.Text.Area()
+}
+
+func (l *Label) Update() {
+	if l.value != "" {
+		l.Text.Place()
+	}
+}
+
+func (l *Label) Hover()           { return }
+func (l *Label) Click(button int) { return }
+func (l *Label) Scroll(delta int) { return }
+
+func (l *Label) Unload() {
+	l.Text.Unload()
+}
@@ -0,0 +1,170 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultTextSize  = .04
+	defaultRowHeight = .05
+)
+
+//Register the widgets in this package so they can be used in gui files
+func init() {
+	engine.RegisterWidgetType("button", buttonFromDefinition)
+	engine.RegisterWidgetType("image", imageFromDefinition)
+	engine.RegisterWidgetType("slider", sliderFromDefinition)
+	engine.RegisterWidgetType("checkbox", checkboxFromDefinition)
+	engine.RegisterWidgetType("dropdown", dropdownFromDefinition)
+	engine.RegisterWidgetType("textfield", textFieldFromDefinition)
+	engine.RegisterWidgetType("list", listFromDefinition)
+	engine.RegisterWidgetType("panel", panelFromDefinition)
+	engine.RegisterWidgetType("label", labelFromDefinition)
+	engine.RegisterWidgetType("progressbar", progressBarFromDefinition)
+}
+
+func textSize(def *engine.WidgetDefinition) float64 {
+	if def.TextSize == 0 {
+		return defaultTextSize
+	}
+	return def.TextSize
+}
+
+//setTextStyle applies the color, font and localized text id from the
+// definition to the text
+func setTextStyle(text *engine.Text, def *engine.WidgetDefinition, color string) {
+	if c := engine.ParseColor(color); c != nil {
+		text.SetColor(c)
+	}
+	if def.Font != "" {
+		text.SetFontFile(def.Font)
+	}
+	if def.TextID != "" {
+		text.SetTextID(def.TextID)
+	}
+}
+
+func buttonFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	btn := MakeButton(def.Name, def.Text, textSize(def), def.Area())
+	if def.Material != "" {
+		dimensions := btn.BackgroundOverlay.Dimensions
+		btn.BackgroundOverlay = engine.NewOverlay(def.Material, btn.BackgroundOverlay.Color, dimensions)
+		btn.BackgroundHoverOverlay = engine.NewOverlay(def.Material, btn.BackgroundHoverOverlay.Color,
+			dimensions)
+		btn.BackgroundClickOverlay = engine.NewOverlay(def.Material, btn.BackgroundClickOverlay.Color,
+			dimensions)
+	}
+	btn.ShowBackground(engine.ParseBool(def.ShowBackground, true))
+
+	setTextStyle(btn.Text, def, def.Color)
+	setTextStyle(btn.TextHover, def, def.HoverColor)
+	setTextStyle(btn.TextClick, def, def.ClickColor)
+
+	btn.ClickEvent = func(sender string) { event(sender, nil) }
+	return btn, nil
+}
+
+func imageFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	img := MakeImage(def.Name, def.Material, def.Area())
+	if c := engine.ParseColor(def.Color); c != nil {
+		img.Overlay.Color = c
+	}
+	return img, nil
+}
+
+func sliderFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	value, _ := strconv.ParseFloat(def.Value, 32)
+	slider := MakeSlider(def.Name, def.Min, def.Max, float32(value), def.Area())
+	slider.Step = def.Step
+	slider.ChangeEvent = func(sender string, value float32) { event(sender, value) }
+	return slider, nil
+}
+
+func checkboxFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	checkbox := MakeCheckbox(def.Name, def.Text, textSize(def), engine.ParseBool(def.Value, false),
+		def.Area())
+	setTextStyle(checkbox.Label, def, def.Color)
+	checkbox.ChangeEvent = func(sender string, checked bool) { event(sender, checked) }
+	return checkbox, nil
+}
+
+func dropdownFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	dropdown := MakeDropdown(def.Name, def.Items, textSize(def), def.Area())
+	setTextStyle(dropdown.Text, def, def.Color)
+	if index, err := strconv.Atoi(def.Value); err == nil {
+		dropdown.Select(index)
+	}
+	dropdown.SelectEvent = func(sender string, index int) { event(sender, index) }
+	return dropdown, nil
+}
+
+func textFieldFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	field := MakeTextField(def.Name, def.Value, textSize(def), def.Area())
+	setTextStyle(field.Text, def, def.Color)
+	field.ChangeEvent = func(sender, value string) { event(sender, value) }
+	return field, nil
+}
+
+func listFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	rowHeight := def.RowHeight
+	if rowHeight == 0 {
+		rowHeight = defaultRowHeight
+	}
+	list := MakeList(def.Name, def.Items, textSize(def), rowHeight, def.Area())
+	list.SelectEvent = func(sender string, index int) { event(sender, index) }
+	return list, nil
+}
+
+func panelFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	panel := MakePanel(def.Name, def.Area())
+	panel.ShowBackground(engine.ParseBool(def.ShowBackground, true))
+	if c := engine.ParseColor(def.Color); c != nil {
+		panel.Background.Color = c
+	}
+
+	for i := range def.Widgets {
+		widget, err := engine.NewWidgetFromDefinition(def.Widgets[i])
+		if err != nil {
+			panel.Unload()
+			return nil, err
+		}
+		panel.AddWidget(widget)
+	}
+	return panel, nil
+}
+
+func labelFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	label := MakeLabel(def.Name, def.Text, textSize(def), def.Area())
+	setTextStyle(label.Text, def, def.Color)
+	if def.TextID != "" {
+		label.SetTextID(def.TextID)
+	}
+	label.Text.SetAlign(textAlign(def.Align), engine.AlignMiddle)
+	label.Text.SetWrap(engine.ParseBool(def.Wrap, false))
+	return label, nil
+}
+
+func progressBarFromDefinition(def *engine.WidgetDefinition, event engine.GuiEventHandler) (engine.Widget, error) {
+	value, _ := strconv.ParseFloat(def.Value, 32)
+	bar := MakeProgressBar(def.Name, float32(value), def.Area())
+	if c := engine.ParseColor(def.Color); c != nil {
+		bar.Bar.Color = c
+	}
+	return bar, nil
+}
+
+func textAlign(align string) int {
+	switch strings.ToLower(align) {
+	case "center":
+		return engine.AlignCenter
+	case "right":
+		return engine.AlignRight
+	}
+	return engine.AlignLeft
+}
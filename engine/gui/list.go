@@ -0,0 +1,290 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+const listScrollBarWidth = .01
+
+//List is a scrollable list of text items which can be selected with the mouse
+// or navigated with the keyboard while it has focus.  Clicking on an already
+// selected item, or pressing enter, activates it
+type List struct {
+	name          string
+	dimensions    *engine.ScreenArea
+	Background    *engine.Overlay
+	FocusOverlay  *engine.Overlay
+	SelectOverlay *engine.Overlay
+	HoverOverlay  *engine.Overlay
+	ScrollBar     *engine.Overlay
+	items         []string
+	rows          []*engine.Text
+	rowHeight     float32
+	first         int
+	selected      int
+	hoverRow      int
+	focused       bool
+	SelectEvent   func(sender string, index int)
+	ActivateEvent func(sender string, index int)
+}
+
+//MakeList returns a list with the default background and colors
+// The number of visible rows is determined by the height of the dimensions
+// and the rowHeight
+func MakeList(name string, items []string, textSize float64, rowHeight float32,
+	dimensions *engine.ScreenArea) *List {
+	list := &List{
+		name:          name,
+		dimensions:    dimensions,
+		Background:    engine.NewOverlay(defaultBackground, defaultColor(), dimensions),
+		FocusOverlay:  engine.NewOverlay(defaultBackground, hoverColor(), dimensions),
+		SelectOverlay: engine.NewOverlay(defaultBackground, accentColor(), newArea()),
+		HoverOverlay:  engine.NewOverlay(defaultBackground, hoverColor(), newArea()),
+		ScrollBar:     engine.NewOverlay(defaultBackground, accentColor(), newArea()),
+		items:         items,
+		rowHeight:     rowHeight,
+		selected:      -1,
+		hoverRow:      -1,
+	}
+	list.SelectOverlay.Color.SetA(100)
+
+	visible := int(dimensions.Height / rowHeight)
+	if visible < 1 {
+		visible = 1
+	}
+	list.rows = make([]*engine.Text, visible)
+	for i := range list.rows {
+		area := engine.NewScreenArea(0, 0, dimensions.Width-listScrollBarWidth, rowHeight,
+			engine.ScreenRelativeAspect)
+		list.rows[i] = engine.NewText([]string{""}, defaultFont, textSize, textColor(), area)
+		list.rows[i].SetAlign(engine.AlignLeft, engine.AlignMiddle)
+	}
+
+	list.layout()
+	list.refreshRows()
+	return list
+}
+
+func (l *List) Items() []string { return l.items }
+
+//SetItems replaces the items in the list and clears the selection
+func (l *List) SetItems(items []string) {
+	l.items = items
+	l.first = 0
+	l.selected = -1
+	l.refreshRows()
+}
+
+func (l *List) Selected() int { return l.selected }
+
+func (l *List) SelectedItem() string {
+	if l.selected < 0 || l.selected >= len(l.items) {
+		return ""
+	}
+	return l.items[l.selected]
+}
+
+//Select selects the item at index, scrolls it into view
+// and calls the SelectEvent if the selection changed
+func (l *List) Select(index int) {
+	if len(l.items) == 0 {
+		return
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(l.items) {
+		index = len(l.items) - 1
+	}
+	if index == l.selected {
+		return
+	}
+	l.selected = index
+	l.scrollTo(index)
+	if l.SelectEvent != nil {
+		l.SelectEvent(l.name, index)
+	}
+}
+
+func (l *List) activate() {
+	if l.selected >= 0 && l.ActivateEvent != nil {
+		l.ActivateEvent(l.name, l.selected)
+	}
+}
+
+//scrollTo scrolls the list the least amount needed to show the item at index
+func (l *List) scrollTo(index int) {
+	if index < l.first {
+		l.setFirst(index)
+	} else if index >= l.first+len(l.rows) {
+		l.setFirst(index - len(l.rows) + 1)
+	}
+}
+
+func (l *List) setFirst(first int) {
+	max := len(l.items) - len(l.rows)
+	if first > max {
+		first = max
+	}
+	if first < 0 {
+		first = 0
+	}
+	if first != l.first {
+		l.first = first
+		l.refreshRows()
+	}
+}
+
+func (l *List) refreshRows() {
+	for i := range l.rows {
+		if l.first+i < len(l.items) {
+			l.rows[i].SetText(l.items[l.first+i])
+		} else {
+			l.rows[i].SetText("")
+		}
+	}
+}
+
+func (l *List) layout() {
+	x := l.dimensions.X()
+	y := l.dimensions.Position.Y
+
+	for i := range l.rows {
+		area := l.rows[i].Area()
+		area.Position.X = x
+		area.Position.Y = y + l.rowHeight*float32(i)
+		area.Position.RelativeTo = engine.ScreenRelativeAspect
+	}
+}
+
+//rowUnderMouse returns the visible row the mouse is over or -1
+func (l *List) rowUnderMouse() int {
+	x, y := engine.ScreenMousePos(engine.ScreenRelativeAspect)
+	for i := range l.rows {
+		if l.first+i < len(l.items) && l.rows[i].Area().Contains(x, y) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *List) Name() string { return l.name }
+func (l *List) MouseArea() *engine.ScreenArea {
+	return l.dimensions
+}
+
+func (l *List) Hover() {
+	l.hoverRow = l.rowUnderMouse()
+}
+
+func (l *List) Update() {
+	l.layout()
+
+	engine.PushClip(l.dimensions)
+	if l.focused {
+		l.FocusOverlay.Place()
+	} else {
+		l.Background.Place()
+	}
+
+	width := l.dimensions.X2() - l.dimensions.X() - listScrollBarWidth
+	if l.hoverRow >= 0 {
+		setArea(l.HoverOverlay.Dimensions, l.dimensions.X(),
+			l.dimensions.Position.Y+l.rowHeight*float32(l.hoverRow), width, l.rowHeight)
+		l.HoverOverlay.Place()
+	}
+	if l.selected >= l.first && l.selected < l.first+len(l.rows) {
+		setArea(l.SelectOverlay.Dimensions, l.dimensions.X(),
+			l.dimensions.Position.Y+l.rowHeight*float32(l.selected-l.first), width, l.rowHeight)
+		l.SelectOverlay.Place()
+	}
+
+	for i := range l.rows {
+		if l.first+i < len(l.items) {
+			l.rows[i].Place()
+		}
+	}
+
+	if len(l.items) > len(l.rows) {
+		height := l.dimensions.Height * (float32(len(l.rows)) / float32(len(l.items)))
+		top := l.dimensions.Position.Y + l.dimensions.Height*(float32(l.first)/float32(len(l.items)))
+		setArea(l.ScrollBar.Dimensions, l.dimensions.X2()-listScrollBarWidth, top,
+			listScrollBarWidth, height)
+		l.ScrollBar.Place()
+	}
+	engine.PopClip()
+
+	l.hoverRow = -1
+}
+
+func (l *List) Click(button int) {
+	if button != 0 {
+		return
+	}
+	row := l.rowUnderMouse()
+	if row < 0 {
+		return
+	}
+	if l.first+row == l.selected {
+		l.activate()
+		return
+	}
+	l.Select(l.first + row)
+}
+
+func (l *List) Scroll(delta int) {
+	l.setFirst(l.first + delta)
+}
+
+func (l *List) Key(key, state int) bool {
+	switch key {
+	case keyUp:
+		//at the ends of the list, let the focus move to the next widget
+		if l.selected <= 0 {
+			return false
+		}
+		if state == engine.StatePressed {
+			l.Select(l.selected - 1)
+		}
+		return true
+	case keyDown:
+		if l.selected >= len(l.items)-1 {
+			return false
+		}
+		if state == engine.StatePressed {
+			l.Select(l.selected + 1)
+		}
+		return true
+	case keyPageUp, keyPageDown, keyHome, keyEnd, keyEnter:
+	default:
+		return false
+	}
+	if state != engine.StatePressed {
+		return true
+	}
+	switch key {
+	case keyPageUp:
+		l.Select(l.selected - len(l.rows))
+	case keyPageDown:
+		l.Select(l.selected + len(l.rows))
+	case keyHome:
+		l.Select(0)
+	case keyEnd:
+		l.Select(len(l.items) - 1)
+	case keyEnter:
+		l.activate()
+	}
+	return true
+}
+
+func (l *List) SetFocused(value bool) { l.focused = value }
+
+func (l *List) Unload() {
+	for i := range l.rows {
+		l.rows[i].Unload()
+	}
+}
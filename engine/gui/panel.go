@@ -0,0 +1,213 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+//Panel is a container of widgets.  The position of each widget added
+// to a panel is relative to the top left of the panel in screen aspect
+// units, and anything drawn outside of the panel is clipped.
+// Mouse and keyboard input is passed on to the panel's widgets
+type Panel struct {
+	name           string
+	dimensions     *engine.ScreenArea
+	Background     *engine.Overlay
+	showBackground bool
+	widgets        []engine.Widget
+	areas          []*engine.ScreenArea
+	offsets        []*engine.ScreenPosition
+	hover          engine.Widget
+	focus          engine.Widget
+}
+
+//MakePanel returns an empty panel with the default background
+func MakePanel(name string, dimensions *engine.ScreenArea) *Panel {
+	return &Panel{
+		name:           name,
+		dimensions:     dimensions,
+		Background:     engine.NewOverlay(defaultBackground, defaultColor(), dimensions),
+		showBackground: true,
+	}
+}
+
+func (p *Panel) ShowBackground(value bool) {
+	p.showBackground = value
+}
+
+//AddWidget adds a widget to the panel.  The widget's current
+// position is used as its offset from the top left of the panel
+func (p *Panel) AddWidget(widget engine.Widget) {
+	area := widget.MouseArea()
+	p.widgets = append(p.widgets, widget)
+	p.areas = append(p.areas, area)
+	p.offsets = append(p.offsets, engine.NewScreenPosition(area.Position.X, area.Position.Y,
+		engine.ScreenRelativeAspect))
+	p.layout()
+}
+
+//RemoveWidget removes a widget from the panel
+func (p *Panel) RemoveWidget(name string) {
+	for i := 0; i < len(p.widgets); i++ {
+		if p.widgets[i].Name() == name {
+			if p.focus == p.widgets[i] {
+				p.setFocus(nil)
+			}
+			p.widgets = append(p.widgets[:i], p.widgets[i+1:]...)
+			p.areas = append(p.areas[:i], p.areas[i+1:]...)
+			p.offsets = append(p.offsets[:i], p.offsets[i+1:]...)
+			i--
+		}
+	}
+}
+
+func (p *Panel) Widgets() []engine.Widget { return p.widgets }
+
+//layout positions every widget relative to the panel
+func (p *Panel) layout() {
+	x := p.dimensions.X()
+	y := p.dimensions.Position.Y
+	for i := range p.areas {
+		p.areas[i].Position.X = x + p.offsets[i].X
+		p.areas[i].Position.Y = y + p.offsets[i].Y
+		p.areas[i].Position.RelativeTo = engine.ScreenRelativeAspect
+	}
+}
+
+//widgetUnderMouse returns the topmost widget in the panel under the mouse
+func (p *Panel) widgetUnderMouse() engine.Widget {
+	x, y := engine.ScreenMousePos(engine.ScreenRelativeAspect)
+	if !p.dimensions.Contains(x, y) {
+		return nil
+	}
+	for i := len(p.widgets) - 1; i >= 0; i-- {
+		if p.widgets[i].MouseArea().Contains(x, y) {
+			return p.widgets[i]
+		}
+	}
+	return nil
+}
+
+func (p *Panel) setFocus(widget engine.Widget) {
+	if p.focus == widget {
+		return
+	}
+	if focused, ok := p.focus.(engine.FocusWidget); ok {
+		focused.SetFocused(false)
+	}
+	p.focus = widget
+	if focused, ok := widget.(engine.FocusWidget); ok {
+		focused.SetFocused(true)
+	}
+}
+
+func (p *Panel) Name() string { return p.name }
+func (p *Panel) MouseArea() *engine.ScreenArea {
+	return p.dimensions
+}
+
+func (p *Panel) Hover() {
+	p.hover = p.widgetUnderMouse()
+	if p.hover != nil {
+		p.hover.Hover()
+	}
+}
+
+func (p *Panel) Update() {
+	p.layout()
+	engine.PushClip(p.dimensions)
+	if p.showBackground {
+		p.Background.Place()
+	}
+	for i := range p.widgets {
+		p.widgets[i].Update()
+	}
+	engine.PopClip()
+}
+
+func (p *Panel) Click(button int) {
+	if widget := p.widgetUnderMouse(); widget != nil {
+		p.setFocus(widget)
+		widget.Click(button)
+	}
+}
+
+func (p *Panel) Scroll(delta int) {
+	if widget := p.widgetUnderMouse(); widget != nil {
+		widget.Scroll(delta)
+	}
+}
+
+//Key passes keys to the focused widget in the panel, and moves the focus
+// between the panel's widgets with the arrow keys.  Returns false when
+// there's no widget in that direction, so the focus can leave the panel
+func (p *Panel) Key(key, state int) bool {
+	if keyWidget, ok := p.focus.(engine.KeyWidget); ok && keyWidget.Key(key, state) {
+		return true
+	}
+
+	direction := -1
+	switch key {
+	case keyUp:
+		direction = engine.NavUp
+	case keyDown:
+		direction = engine.NavDown
+	case keyLeft:
+		direction = engine.NavLeft
+	case keyRight:
+		direction = engine.NavRight
+	case keyEnter:
+		if p.focus == nil {
+			return false
+		}
+		if state == engine.StatePressed {
+			p.focus.Click(0)
+		}
+		return true
+	default:
+		return false
+	}
+
+	if p.focus == nil {
+		//entering the panel focuses its first widget
+		for i := range p.widgets {
+			if _, ok := p.widgets[i].(engine.FocusWidget); ok {
+				if state == engine.StatePressed {
+					p.setFocus(p.widgets[i])
+				}
+				return true
+			}
+		}
+		return false
+	}
+
+	next := engine.NearestWidget(p.focus, p.widgets, direction)
+	if next == nil {
+		return false
+	}
+	if state == engine.StatePressed {
+		p.setFocus(next)
+	}
+	return true
+}
+
+func (p *Panel) CollectChar(key int) {
+	if charWidget, ok := p.focus.(engine.CharWidget); ok {
+		charWidget.CollectChar(key)
+	}
+}
+
+func (p *Panel) SetFocused(value bool) {
+	if !value {
+		p.setFocus(nil)
+	}
+}
+
+func (p *Panel) Unload() {
+	for i := range p.widgets {
+		p.widgets[i].Unload()
+	}
+}
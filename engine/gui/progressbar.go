@@ -0,0 +1,59 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+//ProgressBar fills from left to right as its value goes from 0 to 1,
+// i.e. health or loading progress
+type ProgressBar struct {
+	name       string
+	dimensions *engine.ScreenArea
+	Background *engine.Overlay
+	Bar        *engine.Overlay
+	value      float32
+}
+
+//MakeProgressBar returns a progress bar with the default background and colors
+func MakeProgressBar(name string, value float32, dimensions *engine.ScreenArea) *ProgressBar {
+	return &ProgressBar{
+		name:       name,
+		dimensions: dimensions,
+		Background: engine.NewOverlay(defaultBackground, defaultColor(), dimensions),
+		Bar:        engine.NewOverlay(defaultBackground, accentColor(), newArea()),
+		value:      clamp(value, 0, 1),
+	}
+}
+
+func (p *ProgressBar) Value() float32 { return p.value }
+
+//SetValue sets how full the bar is from 0 to 1
+func (p *ProgressBar) SetValue(value float32) {
+	p.value = clamp(value, 0, 1)
+}
+
+func (p *ProgressBar) Name() string { return p.name }
+func (p *ProgressBar) MouseArea() *engine.ScreenArea {
+	return p.dimensions
+}
+
+func (p *ProgressBar) Update() {
+	p.Background.Place()
+	if p.value <= 0 {
+		return
+	}
+
+	x := p.dimensions.X()
+	setArea(p.Bar.Dimensions, x, p.dimensions.Position.Y, (p.dimensions.X2()-x)*p.value,
+		p.dimensions.Height)
+	p.Bar.Place()
+}
+
+func (p *ProgressBar) Hover()           { return }
+func (p *ProgressBar) Click(button int) { return }
+func (p *ProgressBar) Scroll(delta int) { return }
+func (p *ProgressBar) Unload()          { return }
@@ -0,0 +1,159 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+const (
+	sliderHandleWidth = .02
+	sliderTrackHeight = .3 //percentage of the slider's height
+)
+
+//Slider lets the player pick a value between Min and Max by dragging a handle
+// a Step of 0 allows any value
+type Slider struct {
+	name        string
+	dimensions  *engine.ScreenArea
+	Track       *engine.Overlay
+	Handle      *engine.Overlay
+	HandleHover *engine.Overlay
+	Min, Max    float32
+	Step        float32
+	value       float32
+	hover       bool
+	focused     bool
+	ChangeEvent func(sender string, value float32)
+}
+
+//MakeSlider returns a slider with the default background and colors
+func MakeSlider(name string, min, max, value float32, dimensions *engine.ScreenArea) *Slider {
+	handleArea := newArea()
+	slider := &Slider{
+		name:        name,
+		dimensions:  dimensions,
+		Track:       engine.NewOverlay(defaultBackground, defaultColor(), newArea()),
+		Handle:      engine.NewOverlay(defaultBackground, accentColor(), handleArea),
+		HandleHover: engine.NewOverlay(defaultBackground, textColor(), handleArea),
+		Min:         min,
+		Max:         max,
+		value:       clamp(value, min, max),
+	}
+	slider.HandleHover.Color.SetA(255)
+	return slider
+}
+
+func (s *Slider) Value() float32 { return s.value }
+
+//SetValue sets the value of the slider, snapping it to the nearest step
+// and calls the ChangeEvent if the value changed
+func (s *Slider) SetValue(value float32) {
+	value = clamp(value, s.Min, s.Max)
+	if s.Step > 0 {
+		steps := int((value-s.Min)/s.Step + 0.5)
+		value = clamp(s.Min+float32(steps)*s.Step, s.Min, s.Max)
+	}
+
+	if value == s.value {
+		return
+	}
+	s.value = value
+	if s.ChangeEvent != nil {
+		s.ChangeEvent(s.name, s.value)
+	}
+}
+
+//step returns the amount a single scroll or key press changes the value
+func (s *Slider) step() float32 {
+	if s.Step > 0 {
+		return s.Step
+	}
+	return (s.Max - s.Min) / 20
+}
+
+func (s *Slider) layout() {
+	x := s.dimensions.X()
+	width := s.dimensions.X2() - x
+	y := s.dimensions.Position.Y
+	height := s.dimensions.Height
+
+	setArea(s.Track.Dimensions, x, y+(height*(1-sliderTrackHeight)/2), width, height*sliderTrackHeight)
+
+	var percent float32
+	if s.Max > s.Min {
+		percent = (s.value - s.Min) / (s.Max - s.Min)
+	}
+	setArea(s.Handle.Dimensions, x+((width-sliderHandleWidth)*percent), y, sliderHandleWidth, height)
+}
+
+func (s *Slider) setFromMouse() {
+	x, _ := engine.ScreenMousePos(engine.ScreenRelativeAspect)
+	left := s.dimensions.X() + sliderHandleWidth/2
+	width := (s.dimensions.X2() - s.dimensions.X()) - sliderHandleWidth
+	if width <= 0 {
+		return
+	}
+	s.SetValue(s.Min + ((x-left)/width)*(s.Max-s.Min))
+}
+
+func (s *Slider) Name() string { return s.name }
+func (s *Slider) MouseArea() *engine.ScreenArea {
+	return s.dimensions
+}
+
+func (s *Slider) Hover() {
+	s.hover = true
+	if engine.MouseButtonDown(0) {
+		s.setFromMouse()
+	}
+}
+
+func (s *Slider) Update() {
+	s.layout()
+	s.Track.Place()
+	if s.hover || s.focused {
+		s.HandleHover.Place()
+	} else {
+		s.Handle.Place()
+	}
+	s.hover = false
+}
+
+func (s *Slider) Click(button int) {
+	if button == 0 {
+		s.setFromMouse()
+	}
+}
+
+func (s *Slider) Scroll(delta int) {
+	s.SetValue(s.value - float32(delta)*s.step())
+}
+
+//Key moves the slider with left and right, up and down are left for
+// moving between widgets
+func (s *Slider) Key(key, state int) bool {
+	if key != keyLeft && key != keyRight && key != keyHome && key != keyEnd {
+		return false
+	}
+	if state != engine.StatePressed {
+		return true
+	}
+	switch key {
+	case keyLeft:
+		s.SetValue(s.value - s.step())
+	case keyRight:
+		s.SetValue(s.value + s.step())
+	case keyHome:
+		s.SetValue(s.Min)
+	case keyEnd:
+		s.SetValue(s.Max)
+	}
+	return true
+}
+
+func (s *Slider) SetFocused(value bool) { s.focused = value }
+
+func (s *Slider) Unload() { return }
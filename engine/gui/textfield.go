@@ -0,0 +1,134 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+const (
+	textFieldCaret      = "_"
+	textFieldCaretBlink = 0.5 //seconds
+)
+
+//TextField is a single line of text the player can type into.
+// It collects characters while it has focus in the gui
+type TextField struct {
+	name            string
+	dimensions      *engine.ScreenArea
+	Background      *engine.Overlay
+	FocusBackground *engine.Overlay
+	Text            *engine.Text
+	MaxLength       int
+	value           []rune
+	focused         bool
+	caretOn         bool
+	caretTime       float64
+	ChangeEvent     func(sender, value string)
+	SubmitEvent     func(sender, value string)
+}
+
+//MakeTextField returns a text field with the default background and colors
+// A MaxLength of 0 doesn't limit the length of the text
+func MakeTextField(name, value string, textSize float64, dimensions *engine.ScreenArea) *TextField {
+	field := &TextField{
+		name:            name,
+		dimensions:      dimensions,
+		Background:      engine.NewOverlay(defaultBackground, defaultColor(), dimensions),
+		FocusBackground: engine.NewOverlay(defaultBackground, hoverColor(), dimensions),
+		Text:            engine.NewText([]string{value}, defaultFont, textSize, textColor(), dimensions),
+		value:           []rune(value),
+	}
+	field.Text.SetAlign(engine.AlignLeft, engine.AlignMiddle)
+	return field
+}
+
+func (t *TextField) Value() string { return string(t.value) }
+
+//SetValue replaces the text in the field and calls the ChangeEvent
+func (t *TextField) SetValue(value string) {
+	t.value = []rune(value)
+	t.changed()
+}
+
+func (t *TextField) changed() {
+	t.refresh()
+	if t.ChangeEvent != nil {
+		t.ChangeEvent(t.name, string(t.value))
+	}
+}
+
+func (t *TextField) refresh() {
+	text := string(t.value)
+	if t.focused && t.caretOn {
+		text += textFieldCaret
+	}
+	t.Text.SetText(text)
+}
+
+//CollectChar is the CharCollector for the text field
+func (t *TextField) CollectChar(key int) {
+	if t.MaxLength > 0 && len(t.value) >= t.MaxLength {
+		return
+	}
+	t.value = append(t.value, rune(key))
+	t.changed()
+}
+
+func (t *TextField) Key(key, state int) bool {
+	if key != keyBackspace && key != keyEnter {
+		return false
+	}
+	if state != engine.StatePressed {
+		return true
+	}
+	switch key {
+	case keyBackspace:
+		if len(t.value) > 0 {
+			t.value = t.value[:len(t.value)-1]
+			t.changed()
+		}
+	case keyEnter:
+		if t.SubmitEvent != nil {
+			t.SubmitEvent(t.name, string(t.value))
+		}
+	}
+	return true
+}
+
+func (t *TextField) SetFocused(value bool) {
+	t.focused = value
+	t.caretOn = value
+	t.caretTime = engine.Time()
+	t.refresh()
+}
+
+func (t *TextField) Name() string { return t.name }
+func (t *TextField) MouseArea() *engine.ScreenArea {
+	return t.dimensions
+}
+
+func (t *TextField) Update() {
+	if t.focused && engine.Time()-t.caretTime >= textFieldCaretBlink {
+		t.caretOn = !t.caretOn
+		t.caretTime = engine.Time()
+		t.refresh()
+	}
+
+	if t.focused {
+		t.FocusBackground.Place()
+	} else {
+		t.Background.Place()
+	}
+	t.Text.Place()
+}
+
+func (t *TextField) Hover()           { return }
+func (t *TextField) Click(button int) { return }
+func (t *TextField) Scroll(delta int) { return }
+
+func (t *TextField) Unload() {
+	t.Text.Unload()
+}
@@ -0,0 +1,54 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package gui
+
+import (
+	"excavation/engine"
+)
+
+//keys used for widget keyboard navigation
+var (
+	keyUp        = engine.KeyInt("Up")
+	keyDown      = engine.KeyInt("Down")
+	keyLeft      = engine.KeyInt("Left")
+	keyRight     = engine.KeyInt("Right")
+	keyEnter     = engine.KeyInt("Enter")
+	keySpace     = engine.KeyInt("Space")
+	keyBackspace = engine.KeyInt("Backspace")
+	keyPageUp    = engine.KeyInt("Pageup")
+	keyPageDown  = engine.KeyInt("Pagedown")
+	keyHome      = engine.KeyInt("Home")
+	keyEnd       = engine.KeyInt("End")
+)
+
+func defaultColor() *engine.Color { return engine.NewColor(118, 118, 118, 255) }
+func hoverColor() *engine.Color   { return engine.NewColor(155, 155, 155, 50) }
+func textColor() *engine.Color    { return engine.NewColor(255, 255, 255, 0) }
+func accentColor() *engine.Color  { return engine.NewColor(200, 200, 200, 255) }
+
+//newArea returns a screen aspect relative area, which is used for the
+// parts of a widget that are positioned from the widget's dimensions
+func newArea() *engine.ScreenArea {
+	return engine.NewScreenArea(0, 0, 0, 0, engine.ScreenRelativeAspect)
+}
+
+//setArea sets the passed in area to the given screen aspect relative position
+func setArea(area *engine.ScreenArea, x, y, width, height float32) {
+	area.Position.X = x
+	area.Position.Y = y
+	area.Position.RelativeTo = engine.ScreenRelativeAspect
+	area.Width = width
+	area.Height = height
+}
+
+func clamp(value, min, max float32) float32 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,323 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Gui files describe a gui and its widgets in either xml or json
+// determined by the file's extension. For example:
+//
+//	<Gui useMouse="true" haltInput="true">
+//		<Bind input="Key_Esc" event="closeMenu" />
+//		<Widget type="button" name="new" textId="menu.newGame" textSize="0.04" event="mainMenu"
+//			x="0.1" y="0.7" width="0.21" height="0.05" anchor="left"
+//			color="75,75,75,255" hoverColor="100,100,100,255" showBackground="false" />
+//	</Gui>
+//
+// Text can be set directly with text, or from the language string tables
+// with textId.
+// Widget types are registered by the packages that implement them with
+// RegisterWidgetType, and events are Go functions registered by name with
+// RegisterGuiEvent
+
+const guiWatchInterval = 1.0 //seconds
+
+//GuiDefinition is the contents of a gui file
+type GuiDefinition struct {
+	XMLName   xml.Name            `xml:"Gui" json:"-"`
+	UseMouse  bool                `xml:"useMouse,attr" json:"useMouse"`
+	HaltInput bool                `xml:"haltInput,attr" json:"haltInput"`
+	Bindings  []*GuiBinding       `xml:"Bind" json:"bindings"`
+	Widgets   []*WidgetDefinition `xml:"Widget" json:"widgets"`
+}
+
+//GuiBinding calls the named event when the input is released
+type GuiBinding struct {
+	Input string `xml:"input,attr" json:"input"`
+	Event string `xml:"event,attr" json:"event"`
+}
+
+//WidgetDefinition is a single widget in a gui file.  Which values are used
+// depends on the widget type
+type WidgetDefinition struct {
+	Type           string              `xml:"type,attr" json:"type"`
+	Name           string              `xml:"name,attr" json:"name"`
+	X              float32             `xml:"x,attr" json:"x"`
+	Y              float32             `xml:"y,attr" json:"y"`
+	Width          float32             `xml:"width,attr" json:"width"`
+	Height         float32             `xml:"height,attr" json:"height"`
+	Anchor         string              `xml:"anchor,attr" json:"anchor"`
+	Text           string              `xml:"text,attr" json:"text"`
+	TextID         string              `xml:"textId,attr" json:"textId"`
+	TextSize       float64             `xml:"textSize,attr" json:"textSize"`
+	Align          string              `xml:"align,attr" json:"align"`
+	Wrap           string              `xml:"wrap,attr" json:"wrap"`
+	Font           string              `xml:"font,attr" json:"font"`
+	Material       string              `xml:"material,attr" json:"material"`
+	Color          string              `xml:"color,attr" json:"color"`
+	HoverColor     string              `xml:"hoverColor,attr" json:"hoverColor"`
+	ClickColor     string              `xml:"clickColor,attr" json:"clickColor"`
+	ShowBackground string              `xml:"showBackground,attr" json:"showBackground"`
+	Event          string              `xml:"event,attr" json:"event"`
+	Min            float32             `xml:"min,attr" json:"min"`
+	Max            float32             `xml:"max,attr" json:"max"`
+	Step           float32             `xml:"step,attr" json:"step"`
+	Value          string              `xml:"value,attr" json:"value"`
+	RowHeight      float32             `xml:"rowHeight,attr" json:"rowHeight"`
+	Items          []string            `xml:"Item" json:"items"`
+	Widgets        []*WidgetDefinition `xml:"Widget" json:"widgets"`
+}
+
+//Area returns the widget's position and size as a ScreenArea
+// anchor is one of left, right or aspect.  Aspect is the default
+func (d *WidgetDefinition) Area() *ScreenArea {
+	relative := ScreenRelativeAspect
+	switch strings.ToLower(d.Anchor) {
+	case "left":
+		relative = ScreenRelativeLeft
+	case "right":
+		relative = ScreenRelativeRight
+	}
+	return NewScreenArea(d.X, d.Y, d.Width, d.Height, relative)
+}
+
+//ParseColor parses a color in the format r,g,b,a
+// returns nil if value is empty or invalid
+func ParseColor(value string) *Color {
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		RaiseError(errors.New("Invalid color " + value + ". Colors must be in the format r,g,b,a"))
+		return nil
+	}
+	var rgba [4]int
+	for i := range parts {
+		c, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+		if err != nil {
+			RaiseError(errors.New("Invalid color " + value + ". Colors must be in the format r,g,b,a"))
+			return nil
+		}
+		rgba[i] = c
+	}
+	return NewColor(rgba[0], rgba[1], rgba[2], rgba[3])
+}
+
+//ParseBool returns the passed in definition value as a bool, or the
+// default value if it's empty
+func ParseBool(value string, defaultValue bool) bool {
+	switch strings.ToLower(value) {
+	case "true", "1":
+		return true
+	case "false", "0":
+		return false
+	}
+	return defaultValue
+}
+
+//GuiEventHandler is called by widgets loaded from gui files.  value depends on
+// the widget, i.e. float32 for sliders, bool for checkboxes, or nil for buttons
+type GuiEventHandler func(sender string, value interface{})
+
+//WidgetFactory builds a widget from its definition.  event calls the event
+// named in the definition
+type WidgetFactory func(def *WidgetDefinition, event GuiEventHandler) (Widget, error)
+
+var (
+	widgetTypes = make(map[string]WidgetFactory)
+	guiEvents   = make(map[string]GuiEventHandler)
+	fileGuis    []*Gui
+	guiLastScan float64
+)
+
+//RegisterWidgetType makes a widget type available to gui files
+func RegisterWidgetType(typeName string, factory WidgetFactory) {
+	widgetTypes[strings.ToLower(typeName)] = factory
+}
+
+//RegisterGuiEvent registers a function to be called by name from gui files
+func RegisterGuiEvent(name string, handler GuiEventHandler) {
+	guiEvents[name] = handler
+}
+
+//guiEvent returns a handler that looks up the named event when it's called
+// so events can be registered after the gui file is loaded
+func guiEvent(name string) GuiEventHandler {
+	return func(sender string, value interface{}) {
+		if name == "" {
+			return
+		}
+		if handler, ok := guiEvents[name]; ok {
+			handler(sender, value)
+			return
+		}
+		RaiseError(errors.New("Gui event " + name + " has not been registered."))
+	}
+}
+
+//NewWidgetFromDefinition builds a widget using its registered widget type
+// Used by container widgets to build their children
+func NewWidgetFromDefinition(def *WidgetDefinition) (Widget, error) {
+	factory, ok := widgetTypes[strings.ToLower(def.Type)]
+	if !ok {
+		return nil, errors.New("Widget type " + def.Type + " not found for widget " + def.Name + ".")
+	}
+	return factory(def, guiEvent(def.Event))
+}
+
+//LoadGuiFile builds a gui from the passed in xml or json file in the data directory.
+// The gui's widgets are rebuilt automatically if the file changes while the
+// game is running
+func LoadGuiFile(file string) (*Gui, error) {
+	gui := NewGui()
+	gui.file = file
+	if err := gui.loadFile(); err != nil {
+		return nil, err
+	}
+	return gui, nil
+}
+
+func readGuiDefinition(file string) (*GuiDefinition, error) {
+	data, err := loadEngineData(file)
+	if err != nil {
+		return nil, err
+	}
+
+	def := new(GuiDefinition)
+	if strings.ToLower(path.Ext(file)) == ".json" {
+		err = json.Unmarshal(data, def)
+	} else {
+		err = xml.Unmarshal(data, def)
+	}
+	if err != nil {
+		return nil, errors.New("Error parsing gui file " + file + ": " + err.Error())
+	}
+	return def, nil
+}
+
+//loadFile builds the gui's widgets from its file. If anything in the file
+// fails to load, the gui is left unchanged
+func (g *Gui) loadFile() error {
+	if info, err := os.Stat(guiFilePath(g.file)); err == nil {
+		g.modTime = info.ModTime()
+	}
+
+	def, err := readGuiDefinition(g.file)
+	if err != nil {
+		return err
+	}
+
+	widgets := make([]Widget, 0, len(def.Widgets))
+	for i := range def.Widgets {
+		widget, err := NewWidgetFromDefinition(def.Widgets[i])
+		if err != nil {
+			for w := range widgets {
+				widgets[w].Unload()
+			}
+			return err
+		}
+		widgets = append(widgets, widget)
+	}
+
+	inputs := g.newGuiInputGroup()
+	for i := range def.Bindings {
+		event := guiEvent(def.Bindings[i].Event)
+		input := def.Bindings[i].Input
+		inputs.bind(func(i *Input) {
+			if state, ok := i.ButtonState(); ok && state == StateReleased {
+				event(input, nil)
+			}
+		}, input, input)
+	}
+
+	for i := range g.Widgets {
+		g.Widgets[i].Unload()
+	}
+	g.SetFocus(nil)
+	g.Widgets = widgets
+	g.UseMouse = def.UseMouse
+	g.HaltInput = def.HaltInput
+	g.inputs = inputs
+	return nil
+}
+
+func guiFilePath(file string) string {
+	if path.IsAbs(file) {
+		return file
+	}
+	return path.Join(dataDir, file)
+}
+
+//ReloadGui rebuilds a gui loaded from a file
+func ReloadGui(gui *Gui) error {
+	if gui.file == "" {
+		return errors.New("Gui was not loaded from a file")
+	}
+	if err := gui.loadFile(); err != nil {
+		return err
+	}
+
+	if len(activeGuis) != 0 && activeGuis[0] == gui {
+		gui.load()
+	}
+	return nil
+}
+
+//watchGuiFile reloads the gui while it's loaded if its file changes
+func watchGuiFile(gui *Gui) {
+	if gui.file == "" {
+		return
+	}
+	for i := range fileGuis {
+		if fileGuis[i] == gui {
+			return
+		}
+	}
+	fileGuis = append(fileGuis, gui)
+}
+
+func unwatchGuiFile(gui *Gui) {
+	for i := range fileGuis {
+		if fileGuis[i] == gui {
+			fileGuis = append(fileGuis[:i], fileGuis[i+1:]...)
+			return
+		}
+	}
+}
+
+//updateGuiFiles reloads any loaded gui files that have changed
+func updateGuiFiles() {
+	if Time()-guiLastScan < guiWatchInterval {
+		return
+	}
+	guiLastScan = Time()
+
+	var info os.FileInfo
+	var err error
+	var modTime time.Time
+	for i := range fileGuis {
+		info, err = os.Stat(guiFilePath(fileGuis[i].file))
+		if err != nil {
+			continue
+		}
+		modTime = info.ModTime()
+		if modTime.After(fileGuis[i].modTime) {
+			fileGuis[i].modTime = modTime
+			if err = ReloadGui(fileGuis[i]); err != nil {
+				RaiseError(err)
+			}
+		}
+	}
+}
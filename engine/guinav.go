@@ -0,0 +1,268 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"github.com/jteeuwen/glfw"
+	"math"
+)
+
+//Keyboard and gamepad navigation for guis
+// Tab and Shift+Tab move the focus through the widgets in the order they
+// were added, the arrow keys move the focus to the nearest widget in that
+// direction and Enter activates the focused widget.  The gamepad controls
+// MenuUpDown, MenuLeftRight, MenuAccept and MenuBack from controls.cfg do
+// the same, with MenuBack calling whatever is bound to Key_Esc in the gui
+
+const (
+	navAxisThreshold = 0.5
+	navRepeatDelay   = 0.4 //seconds before a held direction repeats
+	navRepeatRate    = 0.15
+)
+
+//Directions for spatial navigation
+const (
+	NavUp = iota
+	NavDown
+	NavLeft
+	NavRight
+)
+
+var (
+	keyNavTab     = KeyInt("Tab")
+	keyNavUp      = KeyInt("Up")
+	keyNavDown    = KeyInt("Down")
+	keyNavLeft    = KeyInt("Left")
+	keyNavRight   = KeyInt("Right")
+	keyNavEnter   = KeyInt("Enter")
+	keyNavKPEnter = KeyInt("KPEnter")
+	keyNavEsc     = KeyInt("Esc")
+	keyNavLshift  = KeyInt("Lshift")
+	keyNavRshift  = KeyInt("Rshift")
+)
+
+//navState tracks gamepad input so held buttons and axes only
+// navigate once, then repeat after a delay
+type navState struct {
+	buttons    map[string]int
+	axisKey    int
+	axisRepeat float64
+}
+
+//newGuiInputGroup returns an input group with the gui's key handler
+// and gamepad navigation bindings
+func (g *Gui) newGuiInputGroup() *inputGroup {
+	group := newInputGroup()
+	group.keyHandler = g.handleKey
+	g.nav = &navState{buttons: make(map[string]int), axisKey: -1}
+
+	for _, control := range []string{"MenuAccept", "MenuBack", "MenuUpDown", "MenuLeftRight"} {
+		if _, ok := controlCfg.values[control].(string); ok {
+			group.bindControl(g.handleNavInput, control)
+		}
+	}
+	return group
+}
+
+func (g *Gui) handleKey(key, state int) {
+	if keyWidget, ok := g.focus.(KeyWidget); ok && keyWidget.Key(key, state) {
+		return
+	}
+	if state != StatePressed {
+		return
+	}
+
+	switch key {
+	case keyNavTab:
+		if glfw.Key(keyNavLshift) == glfw.KeyPress || glfw.Key(keyNavRshift) == glfw.KeyPress {
+			g.FocusPrev()
+		} else {
+			g.FocusNext()
+		}
+	case keyNavUp:
+		g.FocusDirection(NavUp)
+	case keyNavDown:
+		g.FocusDirection(NavDown)
+	case keyNavLeft:
+		g.FocusDirection(NavLeft)
+	case keyNavRight:
+		g.FocusDirection(NavRight)
+	case keyNavEnter, keyNavKPEnter:
+		if g.focus != nil {
+			g.focus.Click(0)
+		}
+	}
+}
+
+//handleNavInput turns gamepad input into the matching navigation keys
+func (g *Gui) handleNavInput(input *Input) {
+	if axis, ok := input.JoyAxis(); ok {
+		key := -1
+		switch {
+		case input.ControlName() == "MenuUpDown" && axis < -navAxisThreshold:
+			key = keyNavUp
+		case input.ControlName() == "MenuUpDown" && axis > navAxisThreshold:
+			key = keyNavDown
+		case input.ControlName() == "MenuLeftRight" && axis < -navAxisThreshold:
+			key = keyNavLeft
+		case input.ControlName() == "MenuLeftRight" && axis > navAxisThreshold:
+			key = keyNavRight
+		}
+
+		if key == -1 {
+			//only reset if this axis was the one held
+			if g.nav.axisKey != -1 && navAxisControl(g.nav.axisKey) == input.ControlName() {
+				g.nav.axisKey = -1
+			}
+			return
+		}
+		if key != g.nav.axisKey {
+			g.nav.axisKey = key
+			g.nav.axisRepeat = Time() + navRepeatDelay
+			g.handleKey(key, StatePressed)
+		} else if Time() >= g.nav.axisRepeat {
+			g.nav.axisRepeat = Time() + navRepeatRate
+			g.handleKey(key, StatePressed)
+		}
+		return
+	}
+
+	state, ok := input.ButtonState()
+	if !ok || g.nav.buttons[input.ControlName()] == state {
+		return
+	}
+	g.nav.buttons[input.ControlName()] = state
+
+	switch input.ControlName() {
+	case "MenuAccept":
+		g.handleKey(keyNavEnter, state)
+	case "MenuBack":
+		if state == StateReleased {
+			g.back()
+		}
+	}
+}
+
+func navAxisControl(key int) string {
+	if key == keyNavUp || key == keyNavDown {
+		return "MenuUpDown"
+	}
+	return "MenuLeftRight"
+}
+
+//back calls the handler bound to Esc in the gui, so gamepads can
+// close menus the same way the keyboard does
+func (g *Gui) back() {
+	input, ok := g.inputs.keyInputs[keyNavEsc]
+	if !ok {
+		return
+	}
+	if handler, ok := g.inputs.inputHandlers[input.controlName]; ok {
+		input.State = StateReleased
+		handler(input)
+	}
+}
+
+//focusable returns the widgets in the gui that can take focus
+func focusable(widgets []Widget) []Widget {
+	result := make([]Widget, 0, len(widgets))
+	for i := range widgets {
+		if _, ok := widgets[i].(FocusWidget); ok {
+			result = append(result, widgets[i])
+		}
+	}
+	return result
+}
+
+//FocusNext moves the focus to the next widget in the order they were added
+func (g *Gui) FocusNext() {
+	g.focusStep(1)
+}
+
+//FocusPrev moves the focus to the previous widget in the order they were added
+func (g *Gui) FocusPrev() {
+	g.focusStep(-1)
+}
+
+func (g *Gui) focusStep(step int) {
+	widgets := focusable(g.Widgets)
+	if len(widgets) == 0 {
+		return
+	}
+
+	current := -1
+	for i := range widgets {
+		if widgets[i] == g.focus {
+			current = i
+			break
+		}
+	}
+	if current == -1 {
+		if step > 0 {
+			g.SetFocus(widgets[0])
+		} else {
+			g.SetFocus(widgets[len(widgets)-1])
+		}
+		return
+	}
+
+	g.SetFocus(widgets[(current+step+len(widgets))%len(widgets)])
+}
+
+//FocusDirection moves the focus to the nearest widget in the given direction
+// if nothing has focus, the first widget is focused
+func (g *Gui) FocusDirection(direction int) {
+	if g.focus == nil {
+		g.FocusNext()
+		return
+	}
+	if next := NearestWidget(g.focus, g.Widgets, direction); next != nil {
+		g.SetFocus(next)
+	}
+}
+
+//NearestWidget returns the focusable widget closest to from in the given
+// direction, or nil if there isn't one.  Distance off of the direction's
+// axis counts more than distance along it, so navigation favors widgets
+// that are lined up
+func NearestWidget(from Widget, widgets []Widget, direction int) Widget {
+	fromX, fromY := areaCenter(from.MouseArea())
+
+	var nearest Widget
+	best := math.MaxFloat64
+	for _, widget := range focusable(widgets) {
+		if widget == from {
+			continue
+		}
+		x, y := areaCenter(widget.MouseArea())
+		dx, dy := float64(x-fromX), float64(y-fromY)
+
+		var along, across float64
+		switch direction {
+		case NavUp:
+			along, across = -dy, dx
+		case NavDown:
+			along, across = dy, dx
+		case NavLeft:
+			along, across = -dx, dy
+		case NavRight:
+			along, across = dx, dy
+		}
+		if along <= 0 {
+			continue
+		}
+
+		distance := along + math.Abs(across)*2
+		if distance < best {
+			best = distance
+			nearest = widget
+		}
+	}
+	return nearest
+}
+
+func areaCenter(area *ScreenArea) (x, y float32) {
+	return (area.X() + area.X2()) / 2, area.Position.Y + area.Height/2
+}
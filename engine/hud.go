@@ -0,0 +1,68 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"sort"
+)
+
+//Huds are guis drawn beneath the menu stack that never receive input,
+// so the game keeps its input while they're shown.  Each hud is drawn
+// on a layer, with higher layers drawn over lower ones
+var huds []*Gui
+
+//AddHud shows the gui as a hud on the passed in layer. Huds on the
+// same layer are drawn in the order they were added
+func AddHud(gui *Gui, layer int) {
+	removeHud(gui)
+	gui.layer = layer
+	i := sort.Search(len(huds), func(i int) bool { return huds[i].layer > layer })
+	huds = append(huds, nil)
+	copy(huds[i+1:], huds[i:])
+	huds[i] = gui
+}
+
+//RemoveHud stops showing the hud and unloads its widgets
+func RemoveHud(gui *Gui) {
+	if removeHud(gui) {
+		gui.stopTransition()
+		gui.unloadWidgets()
+	}
+}
+
+func removeHud(gui *Gui) bool {
+	for i := range huds {
+		if huds[i] == gui {
+			huds = append(huds[:i], huds[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//RemoveAllHuds removes every hud
+func RemoveAllHuds() {
+	for i := range huds {
+		huds[i].stopTransition()
+		huds[i].unloadWidgets()
+	}
+	huds = huds[0:0]
+}
+
+//Huds returns the huds currently shown from the bottom layer up
+func Huds() []*Gui {
+	return huds
+}
+
+//Layer is the layer the gui is drawn on if it's a hud
+func (g *Gui) Layer() int { return g.layer }
+
+func updateHuds() {
+	for i := range huds {
+		if !huds[i].Hidden {
+			huds[i].update()
+		}
+	}
+}
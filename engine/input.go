@@ -35,6 +35,12 @@ const (
 var (
 	gameInput    *inputGroup
 	currentInput *inputGroup
+
+	//while game input is suspended only these controls are handled
+	gameInputSuspended bool
+	suspendAllowed     map[string]bool
+	//buttons released by SuspendGameInput, whose real release is dropped
+	forcedRelease = make(map[*Input]bool)
 )
 
 //used to handle different groups of input like those used
@@ -47,6 +53,10 @@ type inputGroup struct {
 	joyBtnInputs    map[int]*Input
 
 	inputHandlers map[string]InputHandler
+
+	//keyHandler receives every key press regardless of bindings
+	// used by guis to pass keys to the focused widget
+	keyHandler func(key, state int)
 }
 
 func loadInputGroup(group *inputGroup) {
@@ -61,11 +71,6 @@ func initInput() {
 	gameInput = newInputGroup()
 	currentInput = gameInput
 
-	glfw.SetKeyCallback(keyCallback)
-	glfw.SetMouseButtonCallback(mouseButtonCallback)
-	glfw.SetMousePosCallback(mousePosCallback)
-	glfw.SetMouseWheelCallback(mouseWheelCallback)
-
 	//Reload configs on write
 	controlCfg.RegisterOnWriteHandler(reloadBindingsFromCfg)
 }
@@ -273,11 +278,92 @@ func newInput(inputName string) *Input {
 // and binds it to an input and ties that input to a function
 func BindInput(function InputHandler, input ...string) {
 	for i := range input {
-		//check if input is controlConfig entry
-		if cfgInput, ok := controlCfg.values[input[i]].(string); ok {
-			gameInput.bind(function, input[i], cfgInput)
-		} else {
-			gameInput.bind(function, input[i], input[i])
+		gameInput.bindControl(function, input[i])
+	}
+}
+
+//UnbindInput removes the bindings of the passed in key names or control
+// config entries, so their handlers are no longer called
+func UnbindInput(input ...string) {
+	for i := range input {
+		gameInput.unbind(input[i])
+	}
+}
+
+func (g *inputGroup) unbind(controlName string) {
+	delete(g.inputHandlers, controlName)
+	for _, inputs := range []map[int]*Input{g.mouseAxisInputs, g.mouseBtnInputs, g.keyInputs,
+		g.joyAxisInputs, g.joyBtnInputs} {
+		for k, input := range inputs {
+			if input.controlName == controlName {
+				delete(inputs, k)
+				delete(forcedRelease, input)
+			}
+		}
+	}
+}
+
+//bindControl binds the input from the control config entry of the
+// same name if there is one, otherwise the input is bound directly
+func (g *inputGroup) bindControl(function InputHandler, input string) {
+	if cfgInput, ok := controlCfg.values[input].(string); ok {
+		g.bind(function, input, cfgInput)
+	} else {
+		g.bind(function, input, input)
+	}
+}
+
+//handle calls the handler bound to the input's control, unless game input
+// is suspended and the control isn't allowed through
+func (g *inputGroup) handle(input *Input) {
+	if g == gameInput && gameInputSuspended && !suspendAllowed[input.controlName] {
+		return
+	}
+	if forcedRelease[input] {
+		delete(forcedRelease, input)
+		if input.State == StateReleased {
+			return
+		}
+	}
+	if function, ok := g.inputHandlers[input.controlName]; ok {
+		function(input)
+	}
+}
+
+//SuspendGameInput stops game input from being handled, except for the
+// passed in controls, used while cutscenes play.  Anything held down is
+// released so nothing is stuck on when input is resumed
+func SuspendGameInput(allowed ...string) {
+	suspendAllowed = make(map[string]bool)
+	for i := range allowed {
+		suspendAllowed[allowed[i]] = true
+	}
+	if !gameInputSuspended {
+		gameInput.releaseHeld()
+	}
+	gameInputSuspended = true
+}
+
+//ResumeGameInput handles game input again after SuspendGameInput
+func ResumeGameInput() {
+	gameInputSuspended = false
+	suspendAllowed = nil
+}
+
+func GameInputSuspended() bool { return gameInputSuspended }
+
+//releaseHeld sends a release to the handlers of every pressed button
+func (g *inputGroup) releaseHeld() {
+	for _, inputs := range []map[int]*Input{g.keyInputs, g.mouseBtnInputs, g.joyBtnInputs} {
+		for _, input := range inputs {
+			if input.State != StatePressed || suspendAllowed[input.controlName] {
+				continue
+			}
+			input.State = StateReleased
+			forcedRelease[input] = true
+			if function, ok := g.inputHandlers[input.controlName]; ok {
+				function(input)
+			}
 		}
 	}
 }
@@ -327,12 +413,13 @@ func (g *inputGroup) addBinding(controlName, inputName string) {
 //keyCallBack handles the glfw callback and executes the configured
 // inputhandler for the given input
 func keyCallback(key, state int) {
+	if currentInput.keyHandler != nil {
+		currentInput.keyHandler(key, state)
+	}
 	input, ok := currentInput.keyInputs[key]
 	if ok {
 		input.State = state
-		if function, ok := currentInput.inputHandlers[input.controlName]; ok {
-			function(input)
-		}
+		currentInput.handle(input)
 	}
 }
 
@@ -342,9 +429,7 @@ func mouseButtonCallback(button, state int) {
 	input, ok := currentInput.mouseBtnInputs[button]
 	if ok {
 		input.State = state
-		if function, ok := currentInput.inputHandlers[input.controlName]; ok {
-			function(input)
-		}
+		currentInput.handle(input)
 	}
 }
 
@@ -355,9 +440,7 @@ func mousePosCallback(x, y int) {
 	if ok {
 		input.X = x
 		input.Y = y
-		if function, ok := currentInput.inputHandlers[input.controlName]; ok {
-			function(input)
-		}
+		currentInput.handle(input)
 	}
 }
 
@@ -365,9 +448,7 @@ func mouseWheelCallback(delta int) {
 	input, ok := currentInput.mouseAxisInputs[MouseAxisWheel]
 	if ok {
 		input.X = delta
-		if function, ok := currentInput.inputHandlers[input.controlName]; ok {
-			function(input)
-		}
+		currentInput.handle(input)
 	}
 }
 
@@ -384,9 +465,7 @@ func joyUpdate() {
 			input, ok = currentInput.joyBtnInputs[i]
 			if ok {
 				input.State = int(curJoystick.buttons[i])
-				if function, ok := currentInput.inputHandlers[input.controlName]; ok {
-					function(input)
-				}
+				currentInput.handle(input)
 			}
 		}
 
@@ -395,9 +474,7 @@ func joyUpdate() {
 			input, ok = currentInput.joyAxisInputs[i]
 			if ok {
 				input.AxisPos = curJoystick.axes[i]
-				if function, ok := currentInput.inputHandlers[input.controlName]; ok {
-					function(input)
-				}
+				currentInput.handle(input)
 			}
 		}
 	}
@@ -411,6 +488,11 @@ func SetMousePos(x, y int) {
 	glfw.SetMousePos(x, y)
 }
 
+//MouseButtonDown returns true if the given mouse button is currently held down
+func MouseButtonDown(button int) bool {
+	return glfw.MouseButton(button) == glfw.KeyPress
+}
+
 func reloadBindingsFromCfg(cfg *Config) {
 	//TODO: Only drop cfg bindings, not direct bindings
 	oldBindings := make(map[string]InputHandler)
@@ -418,7 +500,11 @@ func reloadBindingsFromCfg(cfg *Config) {
 		oldBindings[k] = gameInput.inputHandlers[k]
 	}
 
+	inGame := currentInput == gameInput
 	gameInput = newInputGroup()
+	if inGame {
+		currentInput = gameInput
+	}
 
 	for k := range oldBindings {
 		BindInput(oldBindings[k], k)
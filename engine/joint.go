@@ -0,0 +1,250 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gonewton/newton"
+	"math"
+)
+
+//Joint types
+const (
+	JointHinge = iota
+	JointSlider
+	JointBall
+)
+
+const jointTargetTolerance = 0.001 //how close a motor has to get to its target to stop
+
+var phJoints []*Joint
+
+//Joint connects a child body to a parent body, or to the world if the
+// parent is nil.  Hinges rotate around their pin and sliders move along
+// it, both can be limited and driven by a motor.  Ball joints swing freely
+// within their cone limits.  A joint with a break force is removed once the
+// force on it is greater
+type Joint struct {
+	Type   int
+	Child  *PhysicsBody
+	Parent *PhysicsBody
+
+	BreakForce float32 //0 never breaks
+	OnBreak    func(joint *Joint)
+
+	joint      *newton.Joint
+	limited    bool
+	min, max   float32 //radians for hinges, distance for sliders
+	motor      bool
+	targeting  bool
+	target     float32
+	motorSpeed float32
+	motorForce float32
+}
+
+func parentBody(parent *PhysicsBody) *newton.Body {
+	if parent == nil {
+		return nil
+	}
+	return parent.Body
+}
+
+func addJoint(jointType int, child, parent *PhysicsBody, joint *newton.Joint) *Joint {
+	j := &Joint{
+		Type:   jointType,
+		Child:  child,
+		Parent: parent,
+		joint:  joint,
+	}
+	phJoints = append(phJoints, j)
+	return j
+}
+
+//AddHinge creates a joint rotating the child around the pin direction
+// through the pivot point, both in world space
+func AddHinge(child, parent *PhysicsBody, pivot, pin [3]float32) *Joint {
+	j := addJoint(JointHinge, child, parent,
+		phWorld.CreateHinge(&pivot, &pin, child.Body, parentBody(parent)))
+	j.joint.SetHingeCallback(j.updateHinge)
+	return j
+}
+
+//AddSlider creates a joint moving the child along the pin direction
+// through the pivot point, both in world space
+func AddSlider(child, parent *PhysicsBody, pivot, pin [3]float32) *Joint {
+	j := addJoint(JointSlider, child, parent,
+		phWorld.CreateSlider(&pivot, &pin, child.Body, parentBody(parent)))
+	j.joint.SetSliderCallback(j.updateSlider)
+	return j
+}
+
+//AddBallSocket creates a joint letting the child swing in any direction
+// around the pivot point in world space
+func AddBallSocket(child, parent *PhysicsBody, pivot [3]float32) *Joint {
+	return addJoint(JointBall, child, parent,
+		phWorld.CreateBall(&pivot, child.Body, parentBody(parent)))
+}
+
+//AddFixedJoint holds the child in place relative to the parent, it's
+// only useful with a break force
+func AddFixedJoint(child, parent *PhysicsBody, pivot [3]float32) *Joint {
+	j := AddHinge(child, parent, pivot, [3]float32{0, 1, 0})
+	j.SetLimits(0, 0)
+	return j
+}
+
+//SetLimits limits how far a hinge turns in radians, or a slider moves
+func (j *Joint) SetLimits(min, max float32) {
+	j.limited = true
+	j.min, j.max = min, max
+}
+
+func (j *Joint) ClearLimits() { j.limited = false }
+
+//SetConeLimits limits how far a ball joint swings away from the pin, and
+// twists around it, in radians
+func (j *Joint) SetConeLimits(pin [3]float32, cone, twist float32) {
+	if j.Type == JointBall {
+		j.joint.SetBallConeLimits(&pin, cone, twist)
+	}
+}
+
+//SetMotor drives a hinge or slider at the speed, with up to the max force
+func (j *Joint) SetMotor(speed, maxForce float32) {
+	j.motor = true
+	j.targeting = false
+	j.motorSpeed, j.motorForce = speed, maxForce
+}
+
+//SetMotorTarget drives a hinge or slider to the position at up to the
+// speed, then holds it there with up to the max force
+func (j *Joint) SetMotorTarget(position, speed, maxForce float32) {
+	j.motor = true
+	j.targeting = true
+	j.target = position
+	j.motorSpeed, j.motorForce = speed, maxForce
+}
+
+//StopMotor lets the joint move freely
+func (j *Joint) StopMotor() { j.motor = false }
+
+//Position is the hinge's angle in radians, or the slider's distance
+func (j *Joint) Position() float32 {
+	switch j.Type {
+	case JointHinge:
+		return j.joint.HingeAngle()
+	case JointSlider:
+		return j.joint.SliderPosition()
+	}
+	return 0
+}
+
+//Velocity is the hinge's angular speed, or the slider's speed
+func (j *Joint) Velocity() float32 {
+	switch j.Type {
+	case JointHinge:
+		return j.joint.HingeOmega()
+	case JointSlider:
+		return j.joint.SliderVelocity()
+	}
+	return 0
+}
+
+//Force returns the force the joint is applying to hold the bodies together
+func (j *Joint) Force() [3]float32 {
+	var force [3]float32
+	switch j.Type {
+	case JointHinge:
+		j.joint.HingeForce(&force)
+	case JointSlider:
+		j.joint.SliderForce(&force)
+	case JointBall:
+		j.joint.BallForce(&force)
+	}
+	return force
+}
+
+func (j *Joint) Broken() bool { return j.joint == nil }
+
+//Remove destroys the joint, freeing the bodies
+func (j *Joint) Remove() {
+	if j.joint == nil {
+		return
+	}
+	phWorld.DestroyJoint(j.joint)
+	j.joint = nil
+	for i := range phJoints {
+		if phJoints[i] == j {
+			phJoints = append(phJoints[:i], phJoints[i+1:]...)
+			break
+		}
+	}
+}
+
+func (j *Joint) updateHinge(joint *newton.Joint, desc *newton.HingeSliderUpdateDesc) uint {
+	return j.update(desc, joint.HingeCalculateStopAlpha)
+}
+
+func (j *Joint) updateSlider(joint *newton.Joint, desc *newton.HingeSliderUpdateDesc) uint {
+	return j.update(desc, joint.SliderCalculateStopAccel)
+}
+
+//update keeps the joint within its limits and runs its motor.  Returns 1
+// if the desc was changed
+func (j *Joint) update(desc *newton.HingeSliderUpdateDesc,
+	stop func(desc *newton.HingeSliderUpdateDesc, position float32) float32) uint {
+	position := j.Position()
+	if j.limited {
+		if position < j.min {
+			//only push back towards the limit
+			desc.Accel = stop(desc, j.min)
+			desc.MinFriction = 0
+			return 1
+		}
+		if position > j.max {
+			desc.Accel = stop(desc, j.max)
+			desc.MaxFriction = 0
+			return 1
+		}
+	}
+	if !j.motor || desc.Timestep <= 0 {
+		return 0
+	}
+
+	speed := j.motorSpeed
+	if j.targeting {
+		distance := j.target - position
+		if float32(math.Abs(float64(distance))) < jointTargetTolerance {
+			speed = 0
+		} else {
+			//slow down coming into the target rather than overshooting
+			speed = distance / desc.Timestep
+			if speed > j.motorSpeed {
+				speed = j.motorSpeed
+			} else if speed < -j.motorSpeed {
+				speed = -j.motorSpeed
+			}
+		}
+	}
+	desc.Accel = (speed - j.Velocity()) / desc.Timestep
+	desc.MinFriction = -j.motorForce
+	desc.MaxFriction = j.motorForce
+	return 1
+}
+
+//breakJoints removes joints pulled harder than their break force
+func breakJoints() {
+	joints := append([]*Joint(nil), phJoints...)
+	for _, j := range joints {
+		if j.BreakForce <= 0 || j.joint == nil {
+			continue
+		}
+		if force := j.Force(); vecLength(force) > j.BreakForce {
+			j.Remove()
+			if j.OnBreak != nil {
+				j.OnBreak(j)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gonewton/newton"
+	"errors"
+	"strings"
+)
+
+//Built in collision layers
+const (
+	LayerStatic = iota
+	LayerDynamic
+	LayerPlayer
+	LayerTrigger
+	LayerDebris
+)
+
+const maxCollisionLayers = 32
+
+var (
+	layerNames  []string
+	layerMatrix [maxCollisionLayers]LayerMask //bit set for each layer a layer collides with
+)
+
+func init() {
+	resetCollisionLayers()
+}
+
+//resetCollisionLayers drops any layers added by the last scene, and sets the
+// default collision matrix.  Everything collides except debris with the
+// player and other debris, and trigger volumes with the static world
+func resetCollisionLayers() {
+	layerNames = []string{"static", "dynamic", "player", "trigger", "debris"}
+	for i := range layerMatrix {
+		layerMatrix[i] = AllLayers
+	}
+	SetLayersCollide(LayerDebris, LayerPlayer, false)
+	SetLayersCollide(LayerDebris, LayerDebris, false)
+	SetLayersCollide(LayerTrigger, LayerStatic, false)
+}
+
+//AddCollisionLayer adds a named layer that collides with everything, or
+// returns the existing layer with the name
+func AddCollisionLayer(name string) (int, error) {
+	if layer, ok := CollisionLayerFromName(name); ok {
+		return layer, nil
+	}
+	if len(layerNames) >= maxCollisionLayers {
+		return 0, errors.New("Can't add collision layer " + name + ", there are already 32 layers")
+	}
+	layerNames = append(layerNames, strings.ToLower(name))
+	return len(layerNames) - 1, nil
+}
+
+//CollisionLayerFromName returns the layer with the name, case insensitive
+func CollisionLayerFromName(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i := range layerNames {
+		if layerNames[i] == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+//CollisionLayerName returns the name of the layer
+func CollisionLayerName(layer int) string {
+	if layer < 0 || layer >= len(layerNames) {
+		return ""
+	}
+	return layerNames[layer]
+}
+
+//SetLayersCollide sets whether bodies in the two layers collide
+func SetLayersCollide(a, b int, collide bool) {
+	if collide {
+		layerMatrix[a] |= 1 << uint(b)
+		layerMatrix[b] |= 1 << uint(a)
+	} else {
+		layerMatrix[a] &^= 1 << uint(b)
+		layerMatrix[b] &^= 1 << uint(a)
+	}
+}
+
+//LayersCollide returns true if bodies in the two layers collide
+func LayersCollide(a, b int) bool {
+	return layerMatrix[a].Has(b)
+}
+
+//CollidesWith returns the mask of every layer the layer collides with,
+// for querying what a body in the layer would hit
+func CollidesWith(layer int) LayerMask {
+	return layerMatrix[layer]
+}
+
+//MaskFromNames returns a mask of the named layers
+func MaskFromNames(names ...string) (LayerMask, error) {
+	var mask LayerMask
+	for i := range names {
+		layer, ok := CollisionLayerFromName(names[i])
+		if !ok {
+			return mask, errors.New("Collision layer " + names[i] + " not found")
+		}
+		mask |= 1 << uint(layer)
+	}
+	return mask, nil
+}
+
+func (b *PhysicsBody) Layer() int { return b.layer }
+
+//SetLayer sets which layer the body collides as
+func (b *PhysicsBody) SetLayer(layer int) { b.layer = layer }
+
+func (s *PhysicsScene) Layer() int { return s.layer }
+
+//SetLayer sets which layer the scene collides as
+func (s *PhysicsScene) SetLayer(layer int) { s.layer = layer }
+
+//newtonAABBOverlap stops newton colliding bodies whose layers don't collide
+func newtonAABBOverlap(material *newton.Material, body0, body1 *newton.Body, threadIndex int) int {
+	if LayersCollide(layerOf(body0.UserData()), layerOf(body1.UserData())) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gohorde/horde3d"
+)
+
+const resourceLoadBudget = 1.0 / 30 //seconds spent loading resources each frame
+
+//ResourceLoader loads every resource that isn't loaded yet a few at a
+// time each frame, so the screen can still be drawn while loading.
+// Loading a resource can add new resources that it references, so the
+// total can grow as resources are loaded
+type ResourceLoader struct {
+	pending   []*Resource
+	failed    map[horde3d.H3DRes]bool
+	loaded    int
+	errors    []error
+	cancelled bool
+	done      bool
+	//called after each frame's loading with the number of resources
+	// loaded so far and the total found
+	OnProgress func(loaded, total int)
+	//called once all resources have been attempted, with any errors
+	// from resources that couldn't be loaded, each a *ResourceError
+	OnComplete func(errors []error)
+}
+
+var resourceLoaders []*ResourceLoader
+
+//ResourceError is a resource that couldn't be loaded
+type ResourceError struct {
+	Name string
+	Err  error
+}
+
+func (e *ResourceError) Error() string {
+	return "Unable to load resource " + e.Name + ": " + e.Err.Error()
+}
+
+//LoadResources starts loading all resources that aren't loaded yet
+// over the following frames
+func LoadResources(progress func(loaded, total int), complete func(errors []error)) *ResourceLoader {
+	loader := &ResourceLoader{
+		failed:     make(map[horde3d.H3DRes]bool),
+		OnProgress: progress,
+		OnComplete: complete,
+	}
+	resourceLoaders = append(resourceLoaders, loader)
+	return loader
+}
+
+//Loading returns true if any resource loaders are running
+func Loading() bool {
+	return len(resourceLoaders) != 0
+}
+
+//Cancel stops loading without calling OnComplete
+func (l *ResourceLoader) Cancel() {
+	l.cancelled = true
+}
+
+func (l *ResourceLoader) Cancelled() bool { return l.cancelled }
+func (l *ResourceLoader) Done() bool      { return l.done }
+func (l *ResourceLoader) Errors() []error { return l.errors }
+
+//Loaded is the number of resources loaded so far
+func (l *ResourceLoader) Loaded() int { return l.loaded }
+
+//Total is the number of resources found so far
+func (l *ResourceLoader) Total() int {
+	return l.loaded + len(l.errors) + len(l.pending)
+}
+
+//Progress is the portion of resources found so far that have been
+// attempted, from 0 to 1
+func (l *ResourceLoader) Progress() float32 {
+	total := l.Total()
+	if total == 0 {
+		return 1
+	}
+	return float32(l.loaded+len(l.errors)) / float32(total)
+}
+
+//refresh finds any resources not loaded yet, skipping ones that have failed
+func (l *ResourceLoader) refresh() {
+	notLoaded := ResourcesNotLoaded()
+	l.pending = l.pending[:0]
+	for i := range notLoaded {
+		if !l.failed[notLoaded[i].H3DRes] {
+			l.pending = append(l.pending, notLoaded[i])
+		}
+	}
+}
+
+//step loads resources until the frame's time budget is spent.  Returns
+// true once there's nothing left to load
+func (l *ResourceLoader) step() bool {
+	start := Time()
+	for Time()-start < resourceLoadBudget {
+		if len(l.pending) == 0 {
+			l.refresh()
+			if len(l.pending) == 0 {
+				return true
+			}
+		}
+
+		res := l.pending[0]
+		l.pending = l.pending[1:]
+		if res.IsLoaded() {
+			continue
+		}
+		if err := res.Load(); err != nil {
+			l.failed[res.H3DRes] = true
+			l.errors = append(l.errors, &ResourceError{res.Name(), err})
+			continue
+		}
+		l.loaded++
+	}
+	return false
+}
+
+func cancelResourceLoaders() {
+	for i := range resourceLoaders {
+		resourceLoaders[i].Cancel()
+	}
+	resourceLoaders = nil
+}
+
+//updateResourceLoaders runs each loader for the frame
+func updateResourceLoaders() {
+	if len(resourceLoaders) == 0 {
+		return
+	}
+
+	running := resourceLoaders
+	resourceLoaders = nil
+	for _, loader := range running {
+		if loader.cancelled {
+			continue
+		}
+		finished := loader.step()
+		if loader.OnProgress != nil && !loader.cancelled {
+			loader.OnProgress(loader.loaded, loader.Total())
+		}
+		if loader.cancelled {
+			continue
+		}
+		if finished {
+			loader.done = true
+			if loader.OnComplete != nil {
+				loader.OnComplete(loader.errors)
+			}
+			continue
+		}
+		resourceLoaders = append(resourceLoaders, loader)
+	}
+}
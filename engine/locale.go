@@ -0,0 +1,154 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+)
+
+//String tables are json files in the lang folder of the data directory
+// named after their language, i.e. lang/en.json:
+//
+//	{
+//		"Font": "fonts/ubuntu/Ubuntu-M.ttf",
+//		"Strings": {
+//			"menu.newGame": "New Game",
+//			"options.fov": "Field of View: %.0f"
+//		}
+//	}
+//
+// Font is optional, and if set is used in place of every text's font
+// while the language is in use.  Strings missing from a language are
+// looked up in the default language
+
+const (
+	DefaultLanguage = "en"
+	languageDir     = "lang"
+)
+
+//StringTable is the contents of a language file
+type StringTable struct {
+	Font    string
+	Strings map[string]string
+}
+
+var (
+	language        string
+	languageTable   *StringTable
+	defaultTable    *StringTable
+	languageHandler *ConfigSubscription
+)
+
+//initLanguage loads the language set in the config, and switches
+// languages whenever the setting changes
+func initLanguage(cfg *Config) {
+	if err := SetLanguage(cfg.String("Language")); err != nil {
+		RaiseError(err)
+	}
+
+	if languageHandler != nil {
+		languageHandler.Unsubscribe()
+	}
+	languageHandler = cfg.RegisterOnChangeHandler("Language", func(cfg *Config, name string) {
+		if err := SetLanguage(cfg.String(name)); err != nil {
+			RaiseError(err)
+		}
+	})
+}
+
+func loadStringTable(lang string) (*StringTable, error) {
+	data, err := loadEngineData(path.Join(languageDir, lang+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	table := new(StringTable)
+	if err = json.Unmarshal(data, table); err != nil {
+		return nil, errors.New("Error parsing language file for " + lang + ": " + err.Error())
+	}
+	return table, nil
+}
+
+//SetLanguage loads the string table for the language and updates all
+// localized text to match.  If the language can't be loaded the default
+// language is used
+func SetLanguage(lang string) error {
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+
+	if defaultTable == nil {
+		table, err := loadStringTable(DefaultLanguage)
+		if err != nil {
+			return err
+		}
+		defaultTable = table
+	}
+
+	table := defaultTable
+	var err error
+	if lang != DefaultLanguage {
+		table, err = loadStringTable(lang)
+		if err != nil {
+			table = defaultTable
+			lang = DefaultLanguage
+		}
+	}
+
+	fontChanged := languageTable == nil || table.Font != languageTable.Font
+	language = lang
+	languageTable = table
+
+	for t := range loadedTexts {
+		if fontChanged {
+			t.loadFont()
+		}
+		if t.textID != "" {
+			t.localize()
+		}
+	}
+	return err
+}
+
+//Language returns the language currently in use
+func Language() string { return language }
+
+//Localize returns the string for the id in the current language, or
+// the default language if the current language doesn't have it.  If
+// args are passed, the string is used as a format for them like fmt.Sprintf.
+// If the id isn't in either language, the id itself is returned
+func Localize(id string, args ...interface{}) string {
+	format, ok := lookupString(languageTable, id)
+	if !ok {
+		if format, ok = lookupString(defaultTable, id); !ok {
+			format = id
+		}
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func lookupString(table *StringTable, id string) (string, bool) {
+	if table == nil {
+		return "", false
+	}
+	value, ok := table.Strings[id]
+	return value, ok
+}
+
+//languageFont returns the font file to use in place of the passed in
+// font for the current language
+func languageFont(fontFile string) string {
+	if languageTable != nil && languageTable.Font != "" {
+		return languageTable.Font
+	}
+	return fontFile
+}
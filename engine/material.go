@@ -0,0 +1,240 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gonewton/newton"
+	"math"
+)
+
+const DefaultMaterial = "default"
+
+//MaterialProperties are how two surfaces behave when they touch.  A
+// material's own properties are combined with the other material's unless
+// the pair has been set with SetMaterialPair
+type MaterialProperties struct {
+	StaticFriction  float32
+	KineticFriction float32
+	Elasticity      float32 //bounciness, 0 to 1
+	Softness        float32 //how much the surfaces give, 0 to 1
+}
+
+//DefaultMaterialProperties match newton's defaults
+var DefaultMaterialProperties = MaterialProperties{
+	StaticFriction:  0.9,
+	KineticFriction: 0.5,
+	Elasticity:      0.4,
+	Softness:        0.1,
+}
+
+//PhysicsMaterial is a named newton material group
+type PhysicsMaterial struct {
+	Name       string
+	Properties MaterialProperties
+	id         int
+}
+
+type materialPair struct {
+	a, b int
+}
+
+var (
+	phMaterials     map[string]*PhysicsMaterial
+	phMaterialPairs map[materialPair]MaterialProperties //set explicitly
+	phContacts      []*Contact                          //queued during the world update
+)
+
+//Contact is sent to a body's contact handlers when it touches something
+type Contact struct {
+	Body   *PhysicsBody
+	Other  interface{} //*PhysicsBody or *PhysicsScene
+	Point  [3]float32
+	Normal [3]float32 //pointing away from Other
+	Speed  float32    //closing speed along the normal
+}
+
+type ContactHandler func(contact *Contact)
+
+//ContactSubscription is returned when registering a contact handler, and
+// is used to unregister it
+type ContactSubscription struct {
+	body    *PhysicsBody
+	handler ContactHandler
+}
+
+//RegisterContactHandler registers a function to be called after each
+// physics step the body touches something in
+func (b *PhysicsBody) RegisterContactHandler(handler ContactHandler) *ContactSubscription {
+	sub := &ContactSubscription{body: b, handler: handler}
+	b.contactHandlers = append(b.contactHandlers, sub)
+	return sub
+}
+
+//Unsubscribe stops the handler from being called
+func (s *ContactSubscription) Unsubscribe() {
+	if s.body == nil {
+		return
+	}
+	subs := s.body.contactHandlers
+	for i := range subs {
+		if subs[i] == s {
+			s.body.contactHandlers = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	s.body = nil
+}
+
+//resetMaterials sets up the default material in a new world
+func resetMaterials() {
+	phMaterials = make(map[string]*PhysicsMaterial)
+	phMaterialPairs = make(map[materialPair]MaterialProperties)
+	phContacts = nil
+
+	def := &PhysicsMaterial{
+		Name:       DefaultMaterial,
+		Properties: DefaultMaterialProperties,
+		id:         phWorld.DefaultMaterialGroupID(),
+	}
+	phMaterials[def.Name] = def
+	setupMaterialPair(def, def)
+}
+
+//PhysicsMaterialFromName returns the named material, creating it with the
+// default properties if it doesn't exist yet
+func PhysicsMaterialFromName(name string) *PhysicsMaterial {
+	if name == "" {
+		name = DefaultMaterial
+	}
+	if material, ok := phMaterials[name]; ok {
+		return material
+	}
+
+	material := &PhysicsMaterial{
+		Name:       name,
+		Properties: DefaultMaterialProperties,
+		id:         phWorld.CreateMaterialGroupID(),
+	}
+	phMaterials[name] = material
+	for _, other := range phMaterials {
+		setupMaterialPair(material, other)
+	}
+	return material
+}
+
+//DefinePhysicsMaterial sets the properties of the named material
+func DefinePhysicsMaterial(name string, properties MaterialProperties) *PhysicsMaterial {
+	material := PhysicsMaterialFromName(name)
+	material.Properties = properties
+	for _, other := range phMaterials {
+		setupMaterialPair(material, other)
+	}
+	return material
+}
+
+//SetMaterialPair overrides the combined properties of two materials
+// touching each other
+func SetMaterialPair(a, b string, properties MaterialProperties) {
+	materialA := PhysicsMaterialFromName(a)
+	materialB := PhysicsMaterialFromName(b)
+	phMaterialPairs[pairKey(materialA, materialB)] = properties
+	setupMaterialPair(materialA, materialB)
+}
+
+func pairKey(a, b *PhysicsMaterial) materialPair {
+	if a.id > b.id {
+		a, b = b, a
+	}
+	return materialPair{a.id, b.id}
+}
+
+//pairProperties are the pair's own if set, otherwise the average friction
+// and softness, and the bounciest elasticity
+func pairProperties(a, b *PhysicsMaterial) MaterialProperties {
+	if properties, ok := phMaterialPairs[pairKey(a, b)]; ok {
+		return properties
+	}
+	return MaterialProperties{
+		StaticFriction:  float32(math.Sqrt(float64(a.Properties.StaticFriction * b.Properties.StaticFriction))),
+		KineticFriction: float32(math.Sqrt(float64(a.Properties.KineticFriction * b.Properties.KineticFriction))),
+		Elasticity:      float32(math.Max(float64(a.Properties.Elasticity), float64(b.Properties.Elasticity))),
+		Softness:        (a.Properties.Softness + b.Properties.Softness) / 2,
+	}
+}
+
+func setupMaterialPair(a, b *PhysicsMaterial) {
+	properties := pairProperties(a, b)
+	phWorld.SetMaterialDefaultFriction(a.id, b.id, properties.StaticFriction,
+		properties.KineticFriction)
+	phWorld.SetMaterialDefaultElasticity(a.id, b.id, properties.Elasticity)
+	phWorld.SetMaterialDefaultSoftness(a.id, b.id, properties.Softness)
+	phWorld.SetMaterialCollisionCallback(a.id, b.id, nil, newtonAABBOverlap, newtonContactProcess)
+}
+
+//SetMaterial sets the material used when the body touches something
+func (b *PhysicsBody) SetMaterial(name string) {
+	b.Material = PhysicsMaterialFromName(name)
+	b.Body.SetMaterialGroupID(b.Material.id)
+}
+
+//SetMaterial sets the material used when something touches the scene
+func (s *PhysicsScene) SetMaterial(name string) {
+	s.Material = PhysicsMaterialFromName(name)
+	s.Body.SetMaterialGroupID(s.Material.id)
+}
+
+//newtonContactProcess queues the hardest contact between the two bodies,
+// handlers are called after the world update so they can change the world
+func newtonContactProcess(joint *newton.Joint, timestep float32, threadIndex int) {
+	body0, body1 := joint.Body0(), joint.Body1()
+
+	var hardest *newton.Material
+	speed := float32(-1)
+	for contact := joint.FirstContact(); contact != nil; contact = joint.NextContact(contact) {
+		material := contact.Material()
+		if s := material.ContactNormalSpeed(); s > speed {
+			speed = s
+			hardest = material
+		}
+	}
+	if hardest == nil {
+		return
+	}
+
+	var point, normal [3]float32
+	hardest.ContactPositionAndNormal(body0, &point, &normal)
+
+	if pBody, ok := body0.UserData().(*PhysicsBody); ok && len(pBody.contactHandlers) > 0 {
+		phContacts = append(phContacts, &Contact{
+			Body:   pBody,
+			Other:  body1.UserData(),
+			Point:  point,
+			Normal: normal,
+			Speed:  speed,
+		})
+	}
+	if pBody, ok := body1.UserData().(*PhysicsBody); ok && len(pBody.contactHandlers) > 0 {
+		phContacts = append(phContacts, &Contact{
+			Body:   pBody,
+			Other:  body0.UserData(),
+			Point:  point,
+			Normal: [3]float32{-normal[0], -normal[1], -normal[2]},
+			Speed:  speed,
+		})
+	}
+}
+
+func sendContacts() {
+	contacts := phContacts
+	phContacts = nil
+	for i := range contacts {
+		subs := append([]*ContactSubscription(nil), contacts[i].Body.contactHandlers...)
+		for j := range subs {
+			if subs[j].body != nil {
+				subs[j].handler(contacts[i])
+			}
+		}
+	}
+}
@@ -137,7 +137,19 @@ func (n *Node) SetRelativeTransMat(matrix *vmath.Matrix4) {
 	//reset update frame so that changes to local matrix
 	// will be refreshed from c code
 	n.updateFrame = -1
-	n.SetNodeTransMat(n.relMat.Array())
+	n.SetNodeTransMat(matrix.Array())
+}
+
+//SetAbsoluteTransMat places the node with a world space matrix, which is
+// converted into the space of the node's parent
+func (n *Node) SetAbsoluteTransMat(matrix *[16]float32) {
+	relative := *matrix
+	if parent := n.Parent(); parent.H3DNode != 0 {
+		inverse := affineInverse(parent.AbsoluteTransMat().Array())
+		relative = mulMatrix(&inverse, &relative)
+	}
+	n.updateFrame = -1
+	n.SetNodeTransMat(&relative)
 }
 
 func (n *Node) SetLocalTransform(translate, rotate *vmath.Vector3) {
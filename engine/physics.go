@@ -22,21 +22,38 @@ var (
 	phLastUpdate  float64
 	phAccumulator float64
 	phMatrix      = [16]float32{}
+	phBodies      []*PhysicsBody //dynamic bodies, checked against trigger volumes
 )
 
 type PhysicsScene struct {
 	Node *Node
 	*newton.Body
+	Material *PhysicsMaterial
+	layer    int
 }
 
 type PhysicsBody struct {
 	Node *Node
 	*newton.Body
-	Force vmath.Vector3
+	Material *PhysicsMaterial
+	layer    int
+
+	//accumulated until the physics steps of the next frame
+	force, torque [3]float32
+	gravityScale  float32
+	frozen        bool
+	kinematic     bool
+	mass          float32 //created with, restored when it stops being kinematic
+	inertia       [3]float32
+	scale         [3]float32  //node's absolute scale, newton matrices have none
+	lastMatrix    [16]float32 //where a kinematic body was last placed
+
+	contactHandlers []*ContactSubscription
 }
 
 func InitPhysics() {
 	phWorld = newton.CreateWorld()
+	resetMaterials()
 }
 
 func PhysicsWorld() *newton.World {
@@ -48,37 +65,93 @@ func updatePhysics() {
 	frameTime := newTime - phLastUpdate
 	phLastUpdate = newTime
 
+	syncKinematicBodies(float32(frameTime))
+
 	phAccumulator += frameTime
+	stepped := false
 	for phAccumulator >= PHYSICS_DT {
 		phWorld.Update(PHYSICS_DT)
 		phAccumulator -= PHYSICS_DT
+		stepped = true
+	}
+	if stepped {
+		//forces are kept through frames without a step, until a step uses them
+		for i := range phBodies {
+			phBodies[i].clearForces()
+		}
 	}
+	sendContacts()
+	breakJoints()
+	updateTriggerVolumes()
 }
 
 func NewtonApplyForceAndTorque(body *newton.Body, timestep float32, threadIndex int) {
 	var Ixx, Iyy, Izz, mass float32
 
 	body.MassMatrix(&mass, &Ixx, &Iyy, &Izz)
-	body.SetForce(&[3]float32{0.0, mass * GRAVITY, 0.0})
-
 	pBody := body.UserData().(*PhysicsBody)
-	body.AddForce(pBody.Force.Array())
 
+	body.SetForce(&[3]float32{0.0, mass * GRAVITY * pBody.gravityScale, 0.0})
+	body.AddForce(&pBody.force)
+	body.SetTorque(&pBody.torque)
 }
 
+//NewtonTransformUpdate moves the node to where newton put the body.  Newton's
+// matrix is absolute and unscaled, so it's scaled back to the node's size and
+// moved into the parent's space
 func NewtonTransformUpdate(body *newton.Body, matrix *[16]float32, threadIndex int) {
-	body.Matrix(&phMatrix)
-	//TODO: Translate abs physics matrix to relative matrix or assume no children?
 	//TODO: interpolate visual position from physics
-
 	pBody := body.UserData().(*PhysicsBody)
-	//Can only set relative matrix
-	pBody.Node.SetNodeTransMat(&phMatrix)
+
+	phMatrix = *matrix
+	scaleMatrix(&phMatrix, pBody.scale)
+	pBody.Node.SetAbsoluteTransMat(&phMatrix)
+}
+
+//syncKinematicBodies moves kinematic bodies to their nodes, with the
+// velocity that got them there so they push dynamic bodies along
+func syncKinematicBodies(dt float32) {
+	if dt <= 0 {
+		return
+	}
+	for _, b := range phBodies {
+		if !b.kinematic {
+			continue
+		}
+		matrix := rigidMatrix(b.Node.AbsoluteTransMat().Array())
+		if matrix == b.lastMatrix {
+			b.Body.SetVelocity(&[3]float32{})
+			b.Body.SetOmega(&[3]float32{})
+			continue
+		}
+
+		velocity := [3]float32{(matrix[12] - b.lastMatrix[12]) / dt,
+			(matrix[13] - b.lastMatrix[13]) / dt, (matrix[14] - b.lastMatrix[14]) / dt}
+		omega := rotationDelta(&b.lastMatrix, &matrix, dt)
+		b.Body.SetMatrix(&matrix)
+		b.Body.SetVelocity(&velocity)
+		b.Body.SetOmega(&omega)
+		b.lastMatrix = matrix
+	}
 }
 
 func clearAllPhysics() {
 	phWorld.Destroy()
 	phWorld = newton.CreateWorld()
+	resetMaterials()
+	resetCollisionLayers()
+	phBodies = nil
+	phJoints = nil
+	characters = nil
+	triggerVolumes = nil
+}
+
+//collide returns true if the two collision shapes overlap at the matrices
+func collide(a *newton.Collision, matrixA *[16]float32, b *newton.Collision, matrixB *[16]float32) bool {
+	var contacts, normals [3]float32
+	var penetration [1]float32
+	return phWorld.CollisionCollide(1, a, matrixA, b, matrixB, contacts[:], normals[:],
+		penetration[:], 0) > 0
 }
 
 //Allows me to share face access code between scene trees and regular meshes
@@ -217,6 +290,8 @@ func AddPhysicsScene(node *Node) *PhysicsScene {
 	collision := NewtonTreeFromNode(node)
 
 	newScene.Body = phWorld.CreateDynamicBody(collision, node.AbsoluteTransMat().Array())
+	newScene.Body.SetUserData(newScene)
+	newScene.Material = phMaterials[DefaultMaterial]
 
 	return newScene
 }
@@ -261,11 +336,15 @@ func AddPhysicsBodyFromCollision(node *Node, collision *newton.Collision, mass f
 	origin := &[3]float32{}
 
 	newBody.Node = node
+	newBody.scale = matrixScale(node.AbsoluteTransMat().Array())
+	newBody.lastMatrix = rigidMatrix(node.AbsoluteTransMat().Array())
 
-	body := phWorld.CreateDynamicBody(collision, node.AbsoluteTransMat().Array())
+	body := phWorld.CreateDynamicBody(collision, &newBody.lastMatrix)
 
 	collision.CalculateInertialMatrix(inertia, origin)
-	body.SetMassMatrix(mass, mass*inertia[0], mass*inertia[1], mass*inertia[2])
+	newBody.mass = mass
+	newBody.inertia = [3]float32{mass * inertia[0], mass * inertia[1], mass * inertia[2]}
+	body.SetMassMatrix(mass, newBody.inertia[0], newBody.inertia[1], newBody.inertia[2])
 
 	body.SetCentreOfMass(origin)
 
@@ -274,6 +353,10 @@ func AddPhysicsBodyFromCollision(node *Node, collision *newton.Collision, mass f
 	body.SetUserData(newBody)
 
 	newBody.Body = body
+	newBody.Material = phMaterials[DefaultMaterial]
+	newBody.layer = LayerDynamic
+	newBody.gravityScale = 1
+	phBodies = append(phBodies, newBody)
 
 	return newBody
 }
@@ -294,6 +377,8 @@ func AddPhysicsSceneFromFile(node *Node, collisionFile string) *PhysicsScene {
 	collision := LoadCollisionFromFile(collisionFile)
 
 	newScene.Body = phWorld.CreateDynamicBody(collision, node.AbsoluteTransMat().Array())
+	newScene.Body.SetUserData(newScene)
+	newScene.Material = phMaterials[DefaultMaterial]
 
 	return newScene
 }
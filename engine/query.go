@@ -0,0 +1,188 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gonewton/newton"
+	"sort"
+)
+
+const maxSweepContacts = 16
+
+//LayerMask has a bit set for each collision layer a query includes
+type LayerMask uint32
+
+const AllLayers LayerMask = 0xffffffff
+
+//Has returns true if the layer is in the mask
+func (m LayerMask) Has(layer int) bool { return m&(1<<uint(layer)) != 0 }
+
+//QueryHit is what a physics query found
+type QueryHit struct {
+	Body     interface{} //*PhysicsBody or *PhysicsScene
+	Point    [3]float32
+	Normal   [3]float32
+	Distance float32 //from the start of the ray or sweep
+}
+
+type byDistance []QueryHit
+
+func (h byDistance) Len() int           { return len(h) }
+func (h byDistance) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h byDistance) Less(i, j int) bool { return h[i].Distance < h[j].Distance }
+
+//layerOf returns the collision layer of a newton body's user data
+func layerOf(userData interface{}) int {
+	switch body := userData.(type) {
+	case *PhysicsBody:
+		return body.layer
+	case *PhysicsScene:
+		return body.layer
+	}
+	return 0
+}
+
+func queryPrefilter(mask LayerMask) func(body *newton.Body, collision *newton.Collision,
+	userData interface{}) bool {
+	return func(body *newton.Body, collision *newton.Collision, userData interface{}) bool {
+		return mask.Has(layerOf(body.UserData()))
+	}
+}
+
+//RayCast returns the closest body the ray from start to end hits
+func RayCast(start, end [3]float32, mask LayerMask) (hit QueryHit, ok bool) {
+	ray := add(end, scale(start, -1))
+	length := vecLength(ray)
+	closest := float32(2)
+
+	phWorld.RayCast(&start, &end, func(body *newton.Body, normal *[3]float32, collisionID int,
+		userData interface{}, intersectParam float32) float32 {
+		if intersectParam < closest {
+			closest = intersectParam
+			hit = QueryHit{
+				Body:     body.UserData(),
+				Point:    add(start, scale(ray, intersectParam)),
+				Normal:   *normal,
+				Distance: length * intersectParam,
+			}
+		}
+		//only look for closer hits
+		return intersectParam
+	}, nil, queryPrefilter(mask), 0)
+
+	return hit, closest <= 1
+}
+
+//RayCastAll returns every body the ray from start to end hits, closest first
+func RayCastAll(start, end [3]float32, mask LayerMask) []QueryHit {
+	ray := add(end, scale(start, -1))
+	length := vecLength(ray)
+	var hits []QueryHit
+
+	phWorld.RayCast(&start, &end, func(body *newton.Body, normal *[3]float32, collisionID int,
+		userData interface{}, intersectParam float32) float32 {
+		hits = append(hits, QueryHit{
+			Body:     body.UserData(),
+			Point:    add(start, scale(ray, intersectParam)),
+			Normal:   *normal,
+			Distance: length * intersectParam,
+		})
+		//keep going the full length of the ray
+		return 1
+	}, nil, queryPrefilter(mask), 0)
+
+	sort.Sort(byDistance(hits))
+	return hits
+}
+
+//ConvexSweep moves the shape from the matrix to the end position, and returns
+// the first body it hits.  The hit distance is how far the shape moved
+func ConvexSweep(shape *newton.Collision, matrix *[16]float32, end [3]float32,
+	mask LayerMask) (hit QueryHit, ok bool) {
+	start := [3]float32{matrix[12], matrix[13], matrix[14]}
+	fraction, info, ok := convexCast(shape, matrix, end, mask)
+	if !ok {
+		return hit, false
+	}
+	return QueryHit{
+		Body:     info.HitBody.UserData(),
+		Point:    [3]float32{info.Point[0], info.Point[1], info.Point[2]},
+		Normal:   [3]float32{info.Normal[0], info.Normal[1], info.Normal[2]},
+		Distance: vecLength(add(end, scale(start, -1))) * fraction,
+	}, true
+}
+
+//SweepSphere moves a sphere from start to end, and returns the first body
+// it hits
+func SweepSphere(radius float32, start, end [3]float32, mask LayerMask) (QueryHit, bool) {
+	shape := phWorld.CreateSphere(radius, radius, radius, 0, positionMatrix([3]float32{}))
+	defer shape.Release()
+	return ConvexSweep(shape, positionMatrix(start), end, mask)
+}
+
+//SweepBox moves a box of the size, rotated by the matrix, to the end
+// position, and returns the first body it hits
+func SweepBox(size [3]float32, matrix *[16]float32, end [3]float32, mask LayerMask) (QueryHit, bool) {
+	shape := phWorld.CreateBox(size[0], size[1], size[2], 0, positionMatrix([3]float32{}))
+	defer shape.Release()
+	return ConvexSweep(shape, matrix, end, mask)
+}
+
+//convexCast returns the fraction of the move made before the shape hits
+// something, and what it hit
+func convexCast(shape *newton.Collision, matrix *[16]float32, end [3]float32,
+	mask LayerMask) (float32, newton.ConvexCastReturnInfo, bool) {
+	var info [maxSweepContacts]newton.ConvexCastReturnInfo
+	fraction := float32(1)
+	contacts := phWorld.ConvexCast(matrix, &end, shape, &fraction, nil, queryPrefilter(mask),
+		info[:], maxSweepContacts, 0)
+	if contacts == 0 {
+		return 1, info[0], false
+	}
+	return fraction, info[0], true
+}
+
+//Overlap returns every body the shape at the matrix touches
+func Overlap(shape *newton.Collision, matrix *[16]float32, mask LayerMask) []interface{} {
+	var min, max [3]float32
+	shape.CalculateAABB(matrix, &min, &max)
+
+	var bodies []interface{}
+	var bodyMatrix [16]float32
+	phWorld.ForEachBodyInAABBDo(&min, &max, func(body *newton.Body, userData interface{}) {
+		if !mask.Has(layerOf(body.UserData())) {
+			return
+		}
+		body.Matrix(&bodyMatrix)
+		if collide(shape, matrix, body.Collision(), &bodyMatrix) {
+			bodies = append(bodies, body.UserData())
+		}
+	}, nil)
+	return bodies
+}
+
+//OverlapSphere returns every body within the sphere
+func OverlapSphere(centre [3]float32, radius float32, mask LayerMask) []interface{} {
+	shape := phWorld.CreateSphere(radius, radius, radius, 0, positionMatrix([3]float32{}))
+	defer shape.Release()
+	return Overlap(shape, positionMatrix(centre), mask)
+}
+
+//OverlapBox returns every body within the box of the size, placed by the matrix
+func OverlapBox(size [3]float32, matrix *[16]float32, mask LayerMask) []interface{} {
+	shape := phWorld.CreateBox(size[0], size[1], size[2], 0, positionMatrix([3]float32{}))
+	defer shape.Release()
+	return Overlap(shape, matrix, mask)
+}
+
+//positionMatrix is an unrotated matrix at the position
+func positionMatrix(position [3]float32) *[16]float32 {
+	return &[16]float32{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		position[0], position[1], position[2], 1,
+	}
+}
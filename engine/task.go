@@ -89,6 +89,15 @@ func AddTask(name string, function taskFunc, data interface{}, priority int, del
 	tasksSorted = false
 }
 
+//RemoveTask removes every task with the passed in name
+func RemoveTask(name string) {
+	for i := range taskList {
+		if taskList[i].Name == name {
+			taskList[i].Remove()
+		}
+	}
+}
+
 //removeAllTasks removes all active tasks in taskmanager
 func removeAllTasks() {
 	taskList = taskList[0:0]
@@ -6,24 +6,46 @@ package engine
 
 import (
 	"bitbucket.org/tshannon/gohorde/horde3d"
-	"code.google.com/p/freetype-go/freetype"
-	"errors"
-	"image"
-	"image/draw"
-	"strconv"
+	"strings"
 )
 
 const (
-	freeTypeDPI    = 72
 	freeTypeMargin = 0.001
 )
 
-var textIncrementer = 0
+//Horizontal alignment of text in its area
+const (
+	AlignLeft = iota
+	AlignCenter
+	AlignRight
+)
+
+//Vertical alignment of text in its area
+const (
+	AlignTop = iota
+	AlignMiddle
+	AlignBottom
+)
 
-//Text is a freetype rasterized text to a horde overlay
-// an slice of strings is rasterized using 72 dpi
+//loadedTexts holds every text currently loaded so they can be laid
+// out again if the fonts are reloaded
+var loadedTexts = make(map[*Text]bool)
+
+//resetAllText reloads the fonts at the current screen size, and lays
+// out all text again.  Used when the GL context is recreated, the
+// screen is resized or the resources are cleared
+func resetAllText() {
+	unloadFonts()
+	for t := range loadedTexts {
+		t.loadFont()
+	}
+}
+
+//Text is freetype rendered text drawn as horde overlays
+// an slice of strings is drawn from the glyph atlas of the text's font
 // Each entry in the slice is a separate line spaced
 // according to the lineSpacing value 2 = doublespaced
+// Lines are optionally wrapped to the width of the text's area
 // Font Size is not point based, but screen ratio based like the gui system
 type Text struct {
 	text        []string
@@ -31,149 +53,295 @@ type Text struct {
 	size        float64
 	lineSpacing float64
 	fontFile    string
-	overlay     *Overlay
-	textureRes  *Texture
-	context     *freetype.Context
-	background  *image.RGBA
+	font        *Font
+	color       *Color
+	align       int
+	vAlign      int
+	wrap        bool
+	textID      string
+	textArgs    []interface{}
+	//layout
+	dirty        bool
+	glyphs       []placedGlyph
+	bounds       *ScreenArea
+	layoutWidth  float32
+	layoutHeight float32
+	verts        []float32
 }
 
+//placedGlyph is a glyph positioned relative to the top left of the
+// text's area in pixels
+type placedGlyph struct {
+	glyph *glyph
+	x, y  float64
+}
+
+//NewText creates text drawn with the font file, or the language's font if it
+// sets one.  If neither font loads the error is raised and the text is still
+// returned, but draws nothing until its font loads
 func NewText(text []string, fontFile string, size float64,
 	color *Color, area *ScreenArea) *Text {
 
-	size = float64(screenHeight) * size
 	newText := &Text{
 		text:        text,
 		area:        area,
 		size:        size,
 		lineSpacing: 1,
 		fontFile:    fontFile,
+		color:       color,
+		bounds:      NewScreenArea(0, 0, 0, 0, ScreenRelativeAspect),
 	}
 
-	textIncrementer++
-	name := "TextVirtualOverlay_" + strconv.Itoa(textIncrementer)
-	materialData := `<Material>
-		<Shader source="shaders/overlay.shader"/>
-		
-		<Sampler name="albedoMap" map="` + name + `" />
-		</Material>`
+	newText.loadFont()
 
-	newText.overlay = &Overlay{
-		Dimensions: area,
-		Color:      color,
-		Material: &Material{
-			NewVirtualResource(name+".material.xml",
-				ResTypeMaterial, []byte(materialData))},
-	}
+	loadedTexts[newText] = true
+	return newText
+}
 
-	fontData, err := loadEngineData(fontFile)
-	if err != nil {
+//loadFont loads the text's font, falling back to its own font file if the
+// language's font fails
+func (t *Text) loadFont() {
+	file := languageFont(t.fontFile)
+	font, err := LoadFont(file, t.size)
+	if err != nil && file != t.fontFile {
 		RaiseError(err)
-		return nil
+		font, err = LoadFont(t.fontFile, t.size)
 	}
-
-	font, err := freetype.ParseFont(fontData)
 	if err != nil {
 		RaiseError(err)
-		return nil
+		t.font = nil
+		return
 	}
+	t.font = font
+	t.dirty = true
+}
 
-	newText.context = freetype.NewContext()
-	newText.context.SetDPI(freeTypeDPI)
-	newText.context.SetFont(font)
+//layout positions each glyph of the text in its area
+func (t *Text) layout() {
+	t.dirty = false
+	t.layoutWidth = t.area.X2() - t.area.X()
+	t.layoutHeight = t.area.Height
+	t.glyphs = t.glyphs[:0]
+
+	font := t.font
+	areaWidth := float64(t.layoutWidth) * float64(screenHeight)
+	areaHeight := float64(t.layoutHeight) * float64(screenHeight)
+	margin := freeTypeMargin * float64(screenWidth)
+
+	lines := t.text
+	if t.wrap {
+		lines = make([]string, 0, len(t.text))
+		for i := range t.text {
+			lines = append(lines, font.wrap(t.text[i], areaWidth-(margin*2))...)
+		}
+	}
 
-	newText.textureRes = NewVirtualTexture(name, newText.area.PixelWidth(),
-		newText.area.PixelHeight(), horde3d.Formats_TEX_BGRA8, horde3d.ResFlags_NoTexMipmaps)
+	lineHeight := font.size * t.lineSpacing
+	height := 0.0
+	if len(lines) > 0 {
+		height = font.ascent() + font.descent() + lineHeight*float64(len(lines)-1)
+	}
 
-	newText.overlay.Material.Load()
-	newText.overlay.Material.SetResParamI(horde3d.MatRes_SamplerElem, 0, horde3d.MatRes_SampTexResI,
-		int(newText.textureRes.H3DRes))
+	baseline := font.ascent()
+	switch t.vAlign {
+	case AlignMiddle:
+		baseline += (areaHeight - height) / 2
+	case AlignBottom:
+		baseline += areaHeight - height
+	}
 
-	newText.rasterize()
+	left, right := areaWidth, 0.0
+	for _, line := range lines {
+		width := font.measure(line)
+		x := margin
+		switch t.align {
+		case AlignCenter:
+			x = (areaWidth - width) / 2
+		case AlignRight:
+			x = areaWidth - width - margin
+		}
+		left = min64(left, x)
+		right = max64(right, x+width)
+
+		var prev *glyph
+		for _, r := range line {
+			g := font.glyph(r)
+			if prev != nil {
+				x += font.kerning(prev, g)
+			}
+			if g.w > 0 {
+				t.glyphs = append(t.glyphs, placedGlyph{g, x + float64(g.offsetX), baseline + float64(g.offsetY)})
+			}
+			x += g.advance
+			prev = g
+		}
+		baseline += lineHeight
+	}
 
-	newText.overlay.Material.Load()
-	return newText
+	if right < left {
+		left, right = 0, 0
+	}
+	t.bounds.Width = toScreenUnits(right - left)
+	t.bounds.Height = toScreenUnits(height)
+	t.bounds.Position.X = toScreenUnits(left)
+	t.bounds.Position.Y = toScreenUnits(baseline - lineHeight*float64(len(lines)) - font.ascent())
 }
 
-func (t *Text) rasterize() {
-	c := t.context
-	t.context.SetFontSize(t.size)
+func min64(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
 
-	back := image.NewRGBA(image.Rect(0, 0, t.area.PixelWidth(), t.area.PixelHeight()))
-	draw.Draw(back, back.Bounds(), image.Transparent, image.ZP, draw.Src)
+func max64(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
 
-	c.SetClip(back.Bounds())
-	c.SetDst(back)
-	c.SetSrc(image.White)
-	t.background = back
+//updateLayout lays out the text again if it's changed, or its area
+// has been resized
+func (t *Text) updateLayout() {
+	if t.dirty || t.layoutWidth != t.area.X2()-t.area.X() || t.layoutHeight != t.area.Height {
+		t.layout()
+	}
+}
 
-	err := errors.New("")
-	pt := freetype.Pt(int(freeTypeMargin*float32(screenWidth)), int(c.PointToFix32(t.size)>>8))
-	for _, s := range t.text {
-		_, err = c.DrawString(s, pt)
-		if err != nil {
-			RaiseError(err)
-			return
+func (t *Text) Place() {
+	if t.font == nil {
+		return
+	}
+	t.updateLayout()
+	if len(t.glyphs) == 0 {
+		return
+	}
+
+	x, y := t.area.X(), t.area.Position.Y
+	atlasWidth := float32(t.font.atlas.Bounds().Dx())
+	atlasHeight := float32(t.font.atlas.Bounds().Dy())
+
+	var quad [16]float32
+	verts := t.verts[:0]
+	for _, placed := range t.glyphs {
+		g := placed.glyph
+		x1 := x + toScreenUnits(placed.x)
+		y1 := y + toScreenUnits(placed.y)
+		x2 := x1 + toScreenUnits(float64(g.w))
+		y2 := y1 + toScreenUnits(float64(g.h))
+		u1 := float32(g.x) / atlasWidth
+		u2 := float32(g.x+g.w) / atlasWidth
+		v1 := 1 - (float32(g.y) / atlasHeight)
+		v2 := 1 - (float32(g.y+g.h) / atlasHeight)
+
+		quad[0], quad[1], quad[2], quad[3] = x1, y1, u1, v1
+		quad[4], quad[5], quad[6], quad[7] = x1, y2, u1, v2
+		quad[8], quad[9], quad[10], quad[11] = x2, y2, u2, v2
+		quad[12], quad[13], quad[14], quad[15] = x2, y1, u2, v1
+		if placeVertex(quad[:]) {
+			verts = append(verts, quad[:]...)
 		}
-		pt.Y += c.PointToFix32(t.size * t.lineSpacing)
 	}
+	t.verts = verts
 
-	t.textureRes.SetData(t.background)
-	t.textureRes.Load()
+	if len(verts) != 0 {
+		horde3d.ShowOverlays(verts, len(verts)/4, t.color.R(), t.color.G(),
+			t.color.B(), t.color.A()*guiAlpha, t.font.material.H3DRes, 0)
+	}
+}
 
+func (t *Text) Text() []string { return t.text }
+func (t *Text) SetText(text ...string) {
+	t.textID = ""
+	t.textArgs = nil
+	t.text = text
+	t.dirty = true
 }
 
-func (t *Text) Place() {
-	t.overlay.Place()
+//TextID returns the string table id of the text, or an empty string
+// if the text isn't localized
+func (t *Text) TextID() string { return t.textID }
+
+//SetTextID sets the text to the localized string for the id, formatted
+// with the passed in args.  The text is updated if the language changes.
+// Line breaks in the string split the text into separate lines
+func (t *Text) SetTextID(id string, args ...interface{}) {
+	t.textID = id
+	t.textArgs = args
+	t.localize()
 }
 
-func (t *Text) Overlay() *Overlay { return t.overlay }
-func (t *Text) Text() []string    { return t.text }
-func (t *Text) SetText(text ...string) {
-	t.text = text
-	t.rasterize()
+func (t *Text) localize() {
+	t.text = strings.Split(Localize(t.textID, t.textArgs...), "\n")
+	t.dirty = true
 }
 
+//Size is the font size relative to the screen height
 func (t *Text) Size() float64 { return t.size }
 func (t *Text) SetSize(size float64) {
 	t.size = size
-	t.rasterize()
+	t.loadFont()
 }
 
 func (t *Text) LineSpacing() float64 { return t.lineSpacing }
 func (t *Text) SetLineSpacing(spacing float64) {
 	t.lineSpacing = spacing
-	t.rasterize()
+	t.dirty = true
 }
 
 func (t *Text) FontFile() string { return t.fontFile }
 func (t *Text) SetFontFile(file string) {
 	t.fontFile = file
-	fontData, err := loadEngineData(file)
-	if err != nil {
-		RaiseError(err)
-		return
-	}
-
-	font, err := freetype.ParseFont(fontData)
-	if err != nil {
-		RaiseError(err)
-		return
-	}
-
-	t.context.SetFont(font)
-	t.rasterize()
+	t.loadFont()
 }
 
-func (t *Text) Color() *Color         { return t.overlay.Color }
-func (t *Text) SetColor(color *Color) { t.overlay.Color = color }
+func (t *Text) Font() *Font { return t.font }
+
+func (t *Text) Color() *Color         { return t.color }
+func (t *Text) SetColor(color *Color) { t.color = color }
 
 func (t *Text) Area() *ScreenArea { return t.area }
 func (t *Text) SetArea(area *ScreenArea) {
 	t.area = area
-	t.rasterize()
+	t.dirty = true
+}
+
+//Align returns the horizontal and vertical alignment of the text in its area
+func (t *Text) Align() (horizontal, vertical int) { return t.align, t.vAlign }
+
+//SetAlign sets the horizontal alignment (AlignLeft, AlignCenter, AlignRight)
+// and vertical alignment (AlignTop, AlignMiddle, AlignBottom) of the text in its area
+func (t *Text) SetAlign(horizontal, vertical int) {
+	t.align = horizontal
+	t.vAlign = vertical
+	t.dirty = true
+}
+
+func (t *Text) Wrap() bool { return t.wrap }
+
+//SetWrap sets whether lines longer than the width of the text's area
+// are wrapped onto the next line
+func (t *Text) SetWrap(value bool) {
+	t.wrap = value
+	t.dirty = true
+}
+
+//Bounds returns the area the text covers in screen aspect units, which
+// may be larger than the text's area if the text doesn't fit
+func (t *Text) Bounds() *ScreenArea {
+	bounds := NewScreenArea(t.area.X(), t.area.Position.Y, 0, 0, ScreenRelativeAspect)
+	if t.font == nil {
+		return bounds
+	}
+	t.updateLayout()
+	bounds.Position.X += t.bounds.Position.X
+	bounds.Position.Y += t.bounds.Position.Y
+	bounds.Width = t.bounds.Width
+	bounds.Height = t.bounds.Height
+	return bounds
 }
 
 func (t *Text) Unload() {
-	t.overlay.Material.Remove()
-	t.textureRes.Remove()
+	delete(loadedTexts, t)
 }
@@ -0,0 +1,132 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"math"
+)
+
+//Matrices here are column major [16]float32 arrays, the same as horde3d
+// and newton use, with the translation in 12, 13 and 14
+
+//mulMatrix returns a * b
+func mulMatrix(a, b *[16]float32) [16]float32 {
+	var result [16]float32
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float32
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+row] * b[col*4+k]
+			}
+			result[col*4+row] = sum
+		}
+	}
+	return result
+}
+
+//affineInverse inverts a matrix made of rotation, scale and translation
+func affineInverse(m *[16]float32) [16]float32 {
+	//cofactors of the upper 3x3
+	c00 := m[5]*m[10] - m[9]*m[6]
+	c01 := m[9]*m[2] - m[1]*m[10]
+	c02 := m[1]*m[6] - m[5]*m[2]
+	det := m[0]*c00 + m[4]*c01 + m[8]*c02
+	if det == 0 {
+		return identity()
+	}
+	inv := 1 / det
+
+	var result [16]float32
+	result[0] = c00 * inv
+	result[1] = c01 * inv
+	result[2] = c02 * inv
+	result[4] = (m[8]*m[6] - m[4]*m[10]) * inv
+	result[5] = (m[0]*m[10] - m[8]*m[2]) * inv
+	result[6] = (m[4]*m[2] - m[0]*m[6]) * inv
+	result[8] = (m[4]*m[9] - m[8]*m[5]) * inv
+	result[9] = (m[8]*m[1] - m[0]*m[9]) * inv
+	result[10] = (m[0]*m[5] - m[4]*m[1]) * inv
+
+	for row := 0; row < 3; row++ {
+		result[12+row] = -(result[row]*m[12] + result[4+row]*m[13] + result[8+row]*m[14])
+	}
+	result[15] = 1
+	return result
+}
+
+func identity() [16]float32 {
+	return [16]float32{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+//matrixScale returns the length of each axis of the matrix
+func matrixScale(m *[16]float32) [3]float32 {
+	var scale [3]float32
+	for i := range scale {
+		scale[i] = vecLength([3]float32{m[i*4], m[i*4+1], m[i*4+2]})
+	}
+	return scale
+}
+
+//rigidMatrix removes the scale from the matrix, newton only takes rotation
+// and translation
+func rigidMatrix(m *[16]float32) [16]float32 {
+	result := *m
+	scale := matrixScale(m)
+	for i := range scale {
+		if scale[i] == 0 {
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			result[i*4+j] /= scale[i]
+		}
+	}
+	return result
+}
+
+//scaleMatrix scales each axis of the matrix
+func scaleMatrix(m *[16]float32, scale [3]float32) {
+	for i := range scale {
+		for j := 0; j < 3; j++ {
+			m[i*4+j] *= scale[i]
+		}
+	}
+}
+
+//rotationDelta returns the angular velocity that turns the rotation of
+// from into the rotation of to over dt seconds
+func rotationDelta(from, to *[16]float32, dt float32) [3]float32 {
+	//delta = to * transpose(from), only the upper 3x3
+	var d [9]float32
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			var sum float32
+			for k := 0; k < 3; k++ {
+				sum += to[k*4+row] * from[k*4+col]
+			}
+			d[col*3+row] = sum
+		}
+	}
+
+	cos := (d[0] + d[4] + d[8] - 1) / 2
+	if cos > 1 {
+		cos = 1
+	} else if cos < -1 {
+		cos = -1
+	}
+	angle := float32(math.Acos(float64(cos)))
+	if angle < 1e-6 {
+		return [3]float32{}
+	}
+	axis, ok := normalizeVec([3]float32{d[5] - d[7], d[6] - d[2], d[1] - d[3]})
+	if !ok {
+		return [3]float32{}
+	}
+	return scale(axis, angle/dt)
+}
@@ -0,0 +1,150 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bitbucket.org/tshannon/gonewton/newton"
+)
+
+var triggerVolumes []*TriggerVolume
+
+//TriggerVolume is a sensor shape that nothing collides with, it reports
+// physics bodies and character controllers entering and leaving it.
+// Only bodies in layers that collide with LayerTrigger are seen.
+// The shape follows the node.  Other is either a *PhysicsBody or a
+// *CharacterController
+type TriggerVolume struct {
+	Node      *Node
+	collision *newton.Collision
+	inside    map[interface{}]bool
+	enabled   bool
+
+	//Filter returns true if other should be tracked, all are tracked if nil
+	Filter  func(other interface{}) bool
+	OnEnter func(other interface{})
+	OnExit  func(other interface{})
+}
+
+//AddTriggerVolume creates a trigger volume from the collision shape
+// placed at the node
+func AddTriggerVolume(node *Node, collision *newton.Collision) *TriggerVolume {
+	volume := &TriggerVolume{
+		Node:      node,
+		collision: collision,
+		inside:    make(map[interface{}]bool),
+		enabled:   true,
+	}
+	triggerVolumes = append(triggerVolumes, volume)
+	return volume
+}
+
+//AddTriggerVolumeFromNode creates a trigger volume shaped as a convex hull
+// of the node's geometry
+func AddTriggerVolumeFromNode(node *Node) *TriggerVolume {
+	meshes := NewtonMeshListFromNode(node)
+	if len(meshes) == 1 {
+		return AddTriggerVolume(node, phWorld.CreateConvexHullFromMesh(meshes[0], CONVEXTOLERANCE,
+			int(node.H3DNode)))
+	}
+
+	collision := phWorld.CreateCompoundCollision(int(node.H3DNode))
+	collision.CompoundBeginAddRemove()
+	for i := range meshes {
+		collision.CompoundAddSubCollision(phWorld.CreateConvexHullFromMesh(meshes[i],
+			CONVEXTOLERANCE, int(node.H3DNode)))
+	}
+	collision.CompoundEndAddRemove()
+
+	return AddTriggerVolume(node, collision)
+}
+
+//Inside returns the bodies and characters currently inside the volume
+func (t *TriggerVolume) Inside() []interface{} {
+	others := make([]interface{}, 0, len(t.inside))
+	for other := range t.inside {
+		others = append(others, other)
+	}
+	return others
+}
+
+func (t *TriggerVolume) Count() int { return len(t.inside) }
+
+func (t *TriggerVolume) Enabled() bool { return t.enabled }
+
+//SetEnabled turns checking the volume on or off.  Disabling it forgets
+// what is inside without calling OnExit
+func (t *TriggerVolume) SetEnabled(enabled bool) {
+	t.enabled = enabled
+	if !enabled {
+		t.inside = make(map[interface{}]bool)
+	}
+}
+
+//Remove stops the volume being checked
+func (t *TriggerVolume) Remove() {
+	t.enabled = false
+	for i := range triggerVolumes {
+		if triggerVolumes[i] == t {
+			triggerVolumes = append(triggerVolumes[:i], triggerVolumes[i+1:]...)
+			return
+		}
+	}
+}
+
+//check compares what overlaps the volume now to what did last update,
+// OnExit is called before OnEnter
+func (t *TriggerVolume) check() {
+	matrix := rigidMatrix(t.Node.AbsoluteTransMat().Array())
+	current := make(map[interface{}]bool)
+
+	for i := range phBodies {
+		if LayersCollide(LayerTrigger, phBodies[i].layer) && t.track(phBodies[i]) {
+			phBodies[i].Matrix(&phMatrix)
+			if collide(t.collision, &matrix, phBodies[i].Collision(), &phMatrix) {
+				current[phBodies[i]] = true
+			}
+		}
+	}
+	for i := range characters {
+		if characters[i].enabled && LayersCollide(LayerTrigger, LayerPlayer) &&
+			t.track(characters[i]) {
+			if collide(t.collision, &matrix, characters[i].shape(),
+				characters[i].capsuleMatrix()) {
+				current[characters[i]] = true
+			}
+		}
+	}
+
+	for other := range t.inside {
+		if !current[other] {
+			delete(t.inside, other)
+			if t.OnExit != nil {
+				t.OnExit(other)
+			}
+		}
+	}
+	for other := range current {
+		if !t.inside[other] {
+			t.inside[other] = true
+			if t.OnEnter != nil {
+				t.OnEnter(other)
+			}
+		}
+	}
+}
+
+func (t *TriggerVolume) track(other interface{}) bool {
+	return t.Filter == nil || t.Filter(other)
+}
+
+func updateTriggerVolumes() {
+	//callbacks can add or remove volumes
+	volumes := append([]*TriggerVolume(nil), triggerVolumes...)
+	for i := range volumes {
+		if volumes[i].enabled {
+			volumes[i].check()
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"math"
+	"strings"
+)
+
+//Tweens animate a value from a start to an end over a duration.
+// They run on real time rather than game time so guis can animate
+// while the game is paused
+
+//EaseFunc maps linear progress from 0 to 1 to eased progress
+type EaseFunc func(t float64) float64
+
+func EaseLinear(t float64) float64    { return t }
+func EaseInQuad(t float64) float64    { return t * t }
+func EaseOutQuad(t float64) float64   { return t * (2 - t) }
+func EaseInCubic(t float64) float64   { return t * t * t }
+func EaseOutCubic(t float64) float64  { t--; return t*t*t + 1 }
+func EaseInOutSine(t float64) float64 { return -(math.Cos(math.Pi*t) - 1) / 2 }
+
+func EaseInOutQuad(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+func EaseInOutCubic(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return t*t*t/2 + 1
+}
+
+//EaseOutBack overshoots the end slightly before settling
+func EaseOutBack(t float64) float64 {
+	const overshoot = 1.70158
+	t--
+	return t*t*((overshoot+1)*t+overshoot) + 1
+}
+
+var easeNames = map[string]EaseFunc{
+	"linear":     EaseLinear,
+	"inquad":     EaseInQuad,
+	"outquad":    EaseOutQuad,
+	"inoutquad":  EaseInOutQuad,
+	"incubic":    EaseInCubic,
+	"outcubic":   EaseOutCubic,
+	"inoutcubic": EaseInOutCubic,
+	"inoutsine":  EaseInOutSine,
+	"outback":    EaseOutBack,
+}
+
+//EaseFromName returns the ease function with the passed in name, such as
+// linear or inOutSine, so eases can be set in scene and gui files
+func EaseFromName(name string) (EaseFunc, bool) {
+	ease, ok := easeNames[strings.ToLower(name)]
+	return ease, ok
+}
+
+//TweenFunc is called with the eased progress of a tween from 0 to 1
+type TweenFunc func(progress float32)
+
+//Tween calls its function every frame with its eased progress until
+// the duration has passed, then calls OnComplete
+type Tween struct {
+	start      float64
+	duration   float64
+	ease       EaseFunc
+	update     TweenFunc
+	done       bool
+	OnComplete func()
+}
+
+var tweens []*Tween
+
+//NewTween starts a tween lasting duration seconds.  If ease is nil
+// EaseLinear is used
+func NewTween(duration float64, ease EaseFunc, update TweenFunc) *Tween {
+	if ease == nil {
+		ease = EaseLinear
+	}
+	tween := &Tween{
+		start:    Time(),
+		duration: duration,
+		ease:     ease,
+		update:   update,
+	}
+	tween.update(0)
+	tweens = append(tweens, tween)
+	return tween
+}
+
+//Then sets the function called when the tween completes, and returns
+// the tween so it can be chained on creation
+func (t *Tween) Then(complete func()) *Tween {
+	t.OnComplete = complete
+	return t
+}
+
+//Done returns true if the tween has completed or was stopped
+func (t *Tween) Done() bool { return t.done }
+
+//Stop ends the tween where it is without calling OnComplete
+func (t *Tween) Stop() { t.done = true }
+
+//Finish jumps the tween to its end and calls OnComplete
+func (t *Tween) Finish() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.update(1)
+	if t.OnComplete != nil {
+		t.OnComplete()
+	}
+}
+
+func (t *Tween) step(now float64) {
+	if t.done {
+		return
+	}
+	if t.duration <= 0 || now-t.start >= t.duration {
+		t.Finish()
+		return
+	}
+	t.update(float32(t.ease((now - t.start) / t.duration)))
+}
+
+//updateTweens advances every running tween
+func updateTweens() {
+	now := Time()
+	//completion callbacks may start new tweens
+	running := tweens
+	tweens = nil
+	for i := range running {
+		running[i].step(now)
+	}
+
+	active := running[:0]
+	for i := range running {
+		if !running[i].done {
+			active = append(active, running[i])
+		}
+	}
+	tweens = append(active, tweens...)
+}
+
+func lerp32(from, to, progress float32) float32 {
+	return from + (to-from)*progress
+}
+
+//TweenFloat animates the value from its current value to the passed in value
+func TweenFloat(value *float32, to float32, duration float64, ease EaseFunc) *Tween {
+	from := *value
+	return NewTween(duration, ease, func(progress float32) {
+		*value = lerp32(from, to, progress)
+	})
+}
+
+//TweenColor animates the color from its current value to the passed in color
+func TweenColor(color *Color, to *Color, duration float64, ease EaseFunc) *Tween {
+	from := *color
+	target := *to
+	return NewTween(duration, ease, func(progress float32) {
+		color.r = lerpInt(from.r, target.r, progress)
+		color.g = lerpInt(from.g, target.g, progress)
+		color.b = lerpInt(from.b, target.b, progress)
+		color.a = lerpInt(from.a, target.a, progress)
+	})
+}
+
+//TweenAlpha animates the alpha of the color from its current value to
+// the passed in 255 based alpha
+func TweenAlpha(color *Color, to int, duration float64, ease EaseFunc) *Tween {
+	from := color.a
+	return NewTween(duration, ease, func(progress float32) {
+		color.a = lerpInt(from, to, progress)
+	})
+}
+
+func lerpInt(from, to int, progress float32) int {
+	return from + int(math.Floor(float64(float32(to-from)*progress)+0.5))
+}
+
+//TweenPosition moves the area from its current position to the passed in
+// position, which is in the area's current relative positioning
+func TweenPosition(area *ScreenArea, x, y float32, duration float64, ease EaseFunc) *Tween {
+	fromX, fromY := area.Position.X, area.Position.Y
+	return NewTween(duration, ease, func(progress float32) {
+		area.Position.X = lerp32(fromX, x, progress)
+		area.Position.Y = lerp32(fromY, y, progress)
+	})
+}
+
+//TweenSize resizes the area from its current size to the passed in size
+func TweenSize(area *ScreenArea, width, height float32, duration float64, ease EaseFunc) *Tween {
+	fromWidth, fromHeight := area.Width, area.Height
+	return NewTween(duration, ease, func(progress float32) {
+		area.Width = lerp32(fromWidth, width, progress)
+		area.Height = lerp32(fromHeight, height, progress)
+	})
+}
+
+//Transition animates a gui as it's loaded or unloaded.  Guis slide in
+// from the offset when loaded, and out to it when unloaded
+type Transition struct {
+	Duration float64
+	Ease     EaseFunc
+	Fade     bool
+	//screen units
+	OffsetX, OffsetY float32
+}
+
+//FadeTransition fades a gui in or out over the duration
+func FadeTransition(duration float64) *Transition {
+	return &Transition{Duration: duration, Ease: EaseOutQuad, Fade: true}
+}
+
+//SlideTransition slides a gui in from, or out to, the offset in screen units
+func SlideTransition(duration float64, offsetX, offsetY float32) *Transition {
+	return &Transition{Duration: duration, Ease: EaseOutCubic, OffsetX: offsetX, OffsetY: offsetY}
+}
+
+func optionalTransition(transition []*Transition) *Transition {
+	if len(transition) == 0 {
+		return nil
+	}
+	return transition[0]
+}
+
+//transitionIn animates the gui from the transition's start to fully shown
+func (g *Gui) transitionIn(transition *Transition) {
+	g.stopTransition()
+	g.alpha = 1
+	g.offsetX, g.offsetY = 0, 0
+	if transition == nil {
+		return
+	}
+
+	var fromAlpha float32 = 1
+	if transition.Fade {
+		fromAlpha = 0
+	}
+	g.transition = NewTween(transition.Duration, transition.Ease, func(progress float32) {
+		g.alpha = lerp32(fromAlpha, 1, progress)
+		g.offsetX = lerp32(transition.OffsetX, 0, progress)
+		g.offsetY = lerp32(transition.OffsetY, 0, progress)
+	})
+}
+
+//transitionOut animates the gui from where it is to the transition's end
+// and calls done when finished
+func (g *Gui) transitionOut(transition *Transition, done func()) {
+	g.stopTransition()
+	fromAlpha := g.alpha
+	fromX, fromY := g.offsetX, g.offsetY
+
+	var toAlpha float32 = fromAlpha
+	if transition.Fade {
+		toAlpha = 0
+	}
+	g.transition = NewTween(transition.Duration, transition.Ease, func(progress float32) {
+		g.alpha = lerp32(fromAlpha, toAlpha, progress)
+		g.offsetX = lerp32(fromX, transition.OffsetX, progress)
+		g.offsetY = lerp32(fromY, transition.OffsetY, progress)
+	}).Then(done)
+}
+
+func (g *Gui) stopTransition() {
+	if g.transition != nil {
+		g.transition.Stop()
+		g.transition = nil
+	}
+}
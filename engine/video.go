@@ -0,0 +1,171 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"github.com/jteeuwen/glfw"
+)
+
+//videoSettings are the window settings read from the standard config
+type videoSettings struct {
+	width, height int
+	depth         int
+	fullscreen    bool
+	vsync         int
+}
+
+//curVideo is the settings the current window was opened with
+var curVideo videoSettings
+
+func videoSettingsFromCfg(cfg *Config) videoSettings {
+	return videoSettings{
+		width:      cfg.Int("WindowWidth"),
+		height:     cfg.Int("WindowHeight"),
+		depth:      cfg.Int("WindowDepth"),
+		fullscreen: cfg.Bool("Fullscreen"),
+		vsync:      cfg.Int("VSync"),
+	}
+}
+
+func openWindow(settings videoSettings) error {
+	var mode int
+	if settings.fullscreen {
+		mode = glfw.Fullscreen
+	} else {
+		mode = glfw.Windowed
+	}
+
+	if err := glfw.OpenWindow(settings.width, settings.height, 8, 8, 8, 8,
+		settings.depth, 8, mode); err != nil {
+		return err
+	}
+
+	glfw.SetSwapInterval(settings.vsync)
+	glfw.SetWindowTitle(appName)
+	glfw.Disable(glfw.MouseCursor)
+
+	curVideo = settings
+	return nil
+}
+
+//setWindowCallbacks registers the engine's glfw callbacks.  GLFW drops
+// them when a window is closed, so they are set again whenever the
+// window is recreated
+func setWindowCallbacks() {
+	glfw.SetKeyCallback(keyCallback)
+	glfw.SetMouseButtonCallback(mouseButtonCallback)
+	glfw.SetMousePosCallback(mousePosCallback)
+	glfw.SetMouseWheelCallback(mouseWheelCallback)
+	glfw.SetCharCallback(charCollector)
+	glfw.SetWindowSizeCallback(resizeView)
+}
+
+//DisplayMode is a fullscreen resolution supported by the display
+type DisplayMode struct {
+	Width, Height int
+}
+
+const maxDisplayModes = 100
+
+//DisplayModes returns the list of resolutions supported by the display
+// ordered from smallest to largest, with duplicate resolutions at
+// different bit depths removed
+func DisplayModes() []*DisplayMode {
+	vidModes := glfw.VideoModes(maxDisplayModes)
+	modes := make([]*DisplayMode, 0, len(vidModes))
+
+	for i := range vidModes {
+		found := false
+		for m := range modes {
+			if modes[m].Width == vidModes[i].W && modes[m].Height == vidModes[i].H {
+				found = true
+				break
+			}
+		}
+		if !found {
+			modes = append(modes, &DisplayMode{vidModes[i].W, vidModes[i].H})
+		}
+	}
+	return modes
+}
+
+//ApplyVideoSettings applies the WindowWidth, WindowHeight, WindowDepth, Fullscreen
+// and VSync settings from the standard config without restarting the engine.
+// Changes that can be made to the open window are, otherwise the window and GL
+// context are recreated and all resources are re-uploaded.  The current scene,
+// guis and camera are kept. If the new settings can't be applied, the
+// previous settings are restored and an error is returned
+func ApplyVideoSettings() error {
+	settings := videoSettingsFromCfg(Cfg())
+	if settings == curVideo {
+		return nil
+	}
+
+	sizeChanged := settings.width != curVideo.width || settings.height != curVideo.height
+
+	if settings.fullscreen != curVideo.fullscreen || settings.depth != curVideo.depth ||
+		(settings.fullscreen && sizeChanged) {
+		if err := recreateWindow(settings); err != nil {
+			return err
+		}
+	} else {
+		if sizeChanged {
+			glfw.SetWindowSize(settings.width, settings.height)
+		}
+		if settings.vsync != curVideo.vsync {
+			glfw.SetSwapInterval(settings.vsync)
+		}
+		curVideo = settings
+	}
+
+	resetView()
+	return nil
+}
+
+//recreateWindow closes the window and opens a new one with the settings,
+// falling back to the previous settings if it can't be opened.  Resources
+// are re-uploaded to the new GL context, and the font atlases and screen
+// fade are rebuilt
+func recreateWindow(settings videoSettings) error {
+	prevSettings := curVideo
+	mouseX, mouseY := glfw.MousePos()
+
+	//Release everything on the gpu that won't survive the old context
+	// Virtual textures are regenerated by their owners after the reset
+	resList := ResourceList()
+	for i := range resList {
+		if resList[i].Type() == ResTypeTexture && resList[i].IsVirtual() {
+			continue
+		}
+		resList[i].Unload()
+	}
+
+	glfw.CloseWindow()
+
+	openErr := openWindow(settings)
+	if openErr != nil {
+		RaiseError(openErr)
+		if err := openWindow(prevSettings); err != nil {
+			//No window at all, nothing left to render to
+			panic("Unable to reopen window with previous video settings: " + err.Error())
+		}
+	}
+
+	setWindowCallbacks()
+
+	if err := LoadAllResources(); err != nil {
+		RaiseError(err)
+	}
+	resetAllText()
+	unloadScreenFade()
+
+	glfw.SetMousePos(mouseX, mouseY)
+	if len(activeGuis) != 0 {
+		//restore mouse cursor state
+		activeGuis[0].load()
+	}
+
+	return openErr
+}
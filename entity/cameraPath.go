@@ -0,0 +1,271 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"math"
+)
+
+const (
+	defaultPathPriority = 100 //above the player's camera
+	defaultPathEase     = "inOutSine"
+)
+
+//CameraPath moves a camera along a spline through the node's children,
+// in order, over duration seconds.  The camera looks at the lookAt node
+// if one is set, otherwise it faces along the path.
+// Optional args: ease, lookAt, priority, blendIn, blendOut, hold
+// hold keeps the camera at the end of the path until the path is
+// triggered with 0, instead of handing the view back when it ends
+type CameraPath struct {
+	node     *engine.Node
+	camera   *engine.Camera
+	view     *engine.CameraView
+	duration float64
+	ease     engine.EaseFunc
+	lookAt   string
+	blendIn  float64
+	blendOut float64
+	hold     bool
+
+	points  [][3]float32
+	start   float64
+	playing bool
+	playID  int
+}
+
+func (p *CameraPath) Add(node *engine.Node, args EntityArgs) {
+	p.node = node
+	p.duration = float64(args.Float("duration"))
+	if p.duration <= 0 {
+		args.Invalid("duration", "Must be greater than 0")
+		p.duration = 1
+	}
+
+	easeName := defaultPathEase
+	if args.Has("ease") {
+		easeName = args.String("ease")
+	}
+	ease, ok := engine.EaseFromName(easeName)
+	if !ok {
+		args.Invalid("ease", "Unknown ease "+easeName)
+		ease = engine.EaseLinear
+	}
+	p.ease = ease
+
+	if args.Has("lookAt") {
+		p.lookAt = args.String("lookAt")
+	}
+	if args.Has("blendIn") {
+		p.blendIn = float64(args.Float("blendIn"))
+	}
+	if args.Has("blendOut") {
+		p.blendOut = float64(args.Float("blendOut"))
+	}
+	if args.Has("hold") {
+		p.hold = args.Bool("hold")
+	}
+
+	priority := defaultPathPriority
+	if args.Has("priority") {
+		priority = int(args.Float("priority"))
+	}
+
+	if len(node.Children()) < 2 {
+		args.Invalid("", "Camera paths need at least 2 child nodes")
+	}
+
+	p.camera = engine.AddCamera(engine.Root, node.Name()+"_Camera", engine.RenderCamera().Pipeline())
+	p.view = engine.AddCameraView(node.Name(), p.camera, priority)
+}
+
+//Trigger plays the path from the start, or stops it if value is 0
+func (p *CameraPath) Trigger(value float32) {
+	if value <= 0 {
+		p.Stop()
+		return
+	}
+	p.Play()
+}
+
+//Play moves the camera along the path from the start
+func (p *CameraPath) Play() {
+	p.loadPoints()
+	if len(p.points) < 2 {
+		return
+	}
+
+	p.playID++
+	playID := p.playID
+	p.playing = true
+	p.start = engine.GameTime()
+	p.place(0)
+	p.view.Enable(p.blendIn)
+
+	engine.AddTask(p.node.Name()+"_CameraPath", func(t *engine.Task) {
+		if playID != p.playID {
+			t.Remove()
+			return
+		}
+		if !p.update() {
+			t.Remove()
+		}
+	}, p, 0, 0)
+}
+
+//Stop ends the path and hands the view back to the next camera
+func (p *CameraPath) Stop() {
+	p.playID++
+	p.playing = false
+	p.view.Disable(p.blendOut)
+}
+
+func (p *CameraPath) Playing() bool     { return p.playing }
+func (p *CameraPath) Duration() float64 { return p.duration }
+
+//loadPoints reads the current positions of the path's child nodes
+func (p *CameraPath) loadPoints() {
+	children := p.node.Children()
+	p.points = p.points[:0]
+	for i := range children {
+		matrix := children[i].AbsoluteTransMat().Array()
+		p.points = append(p.points, [3]float32{matrix[12], matrix[13], matrix[14]})
+	}
+}
+
+//update moves the camera for the current time, returns false once the
+// path has finished
+func (p *CameraPath) update() bool {
+	progress := (engine.GameTime() - p.start) / p.duration
+	if progress >= 1 {
+		p.place(1)
+		if !p.hold {
+			p.Stop()
+		} else {
+			p.playing = false
+		}
+		return false
+	}
+	p.place(float32(p.ease(progress)))
+	return true
+}
+
+//place moves the camera to the eased progress along the path
+func (p *CameraPath) place(progress float32) {
+	position, tangent := p.pointAt(progress)
+
+	var forward [3]float32
+	if target, ok := p.lookAtPosition(); ok {
+		forward = subtract(target, position)
+	} else {
+		forward = tangent
+	}
+
+	matrix := p.camera.RelativeTransMat()
+	lookRotation(matrix.Array(), forward)
+	array := matrix.Array()
+	array[12], array[13], array[14], array[15] = position[0], position[1], position[2], 1
+	p.camera.SetRelativeTransMat(matrix)
+}
+
+func (p *CameraPath) lookAtPosition() ([3]float32, bool) {
+	if p.lookAt == "" {
+		return [3]float32{}, false
+	}
+	nodes := engine.Root.FindChild(p.lookAt, engine.NodeTypeUndefined)
+	if len(nodes) == 0 {
+		return [3]float32{}, false
+	}
+	matrix := nodes[0].AbsoluteTransMat().Array()
+	return [3]float32{matrix[12], matrix[13], matrix[14]}, true
+}
+
+//pointAt returns the position and direction on the catmull-rom spline
+// through the points at progress from 0 to 1
+func (p *CameraPath) pointAt(progress float32) (position, tangent [3]float32) {
+	segments := len(p.points) - 1
+	u := progress * float32(segments)
+	segment := int(u)
+	if segment >= segments {
+		segment = segments - 1
+	}
+	if segment < 0 {
+		segment = 0
+	}
+	t := u - float32(segment)
+
+	p0 := p.points[maxIndex(segment-1, 0)]
+	p1 := p.points[segment]
+	p2 := p.points[segment+1]
+	p3 := p.points[minIndex(segment+2, segments)]
+
+	t2 := t * t
+	t3 := t2 * t
+	for i := 0; i < 3; i++ {
+		position[i] = 0.5 * ((2 * p1[i]) +
+			(-p0[i]+p2[i])*t +
+			(2*p0[i]-5*p1[i]+4*p2[i]-p3[i])*t2 +
+			(-p0[i]+3*p1[i]-3*p2[i]+p3[i])*t3)
+		tangent[i] = 0.5 * ((-p0[i] + p2[i]) +
+			2*(2*p0[i]-5*p1[i]+4*p2[i]-p3[i])*t +
+			3*(-p0[i]+3*p1[i]-3*p2[i]+p3[i])*t2)
+	}
+	return position, tangent
+}
+
+func maxIndex(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minIndex(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func subtract(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross(a, b [3]float32) [3]float32 {
+	return [3]float32{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+func normalize(v [3]float32) ([3]float32, bool) {
+	length := float32(math.Sqrt(float64(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])))
+	if length < 1e-6 {
+		return v, false
+	}
+	return [3]float32{v[0] / length, v[1] / length, v[2] / length}, true
+}
+
+//lookRotation sets the upper 3x3 of the column major matrix so that the
+// camera, which looks down -z, faces forward with y up.  The rotation is
+// left as is if forward has no length
+func lookRotation(m *[16]float32, forward [3]float32) {
+	back, ok := normalize([3]float32{-forward[0], -forward[1], -forward[2]})
+	if !ok {
+		return
+	}
+	right, ok := normalize(cross([3]float32{0, 1, 0}, back))
+	if !ok {
+		//looking straight up or down
+		right = [3]float32{1, 0, 0}
+	}
+	up := cross(back, right)
+
+	m[0], m[1], m[2], m[3] = right[0], right[1], right[2], 0
+	m[4], m[5], m[6], m[7] = up[0], up[1], up[2], 0
+	m[8], m[9], m[10], m[11] = back[0], back[1], back[2], 0
+}
@@ -0,0 +1,87 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"strings"
+)
+
+//CollisionLayers adds collision layers and changes which layers collide,
+// for physics entities to use with their layer arg.  The built in layers
+// are static, dynamic, player, trigger and debris.  Layers are looked up once
+// the scene is loaded, so it can be anywhere in the scene.
+// Optional args:
+//	layers	comma separated list of layers to add
+//	ignore	pairs of layers that don't collide, separated by ; in the format layer,layer
+//	collide	pairs of layers that do collide, in the same format as ignore
+type CollisionLayers struct {
+}
+
+func (c *CollisionLayers) Add(node *engine.Node, args EntityArgs) {
+	if args.Has("layers") {
+		for _, name := range splitNames(args.String("layers")) {
+			if _, err := engine.AddCollisionLayer(name); err != nil {
+				args.Invalid("layers", err.Error())
+			}
+		}
+	}
+	//pairs can name layers added by other CollisionLayers entities
+	afterLoad(node, func() {
+		if args.Has("ignore") {
+			setLayerPairs(args, "ignore", false)
+		}
+		if args.Has("collide") {
+			setLayerPairs(args, "collide", true)
+		}
+	})
+}
+
+func setLayerPairs(args EntityArgs, argName string, collide bool) {
+	for _, item := range strings.Split(args.String(argName), ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		names := splitNames(item)
+		if len(names) != 2 {
+			args.Invalid(argName, "Pair "+item+" needs two layers")
+			continue
+		}
+		a, aOk := engine.CollisionLayerFromName(names[0])
+		b, bOk := engine.CollisionLayerFromName(names[1])
+		if !aOk || !bOk {
+			args.Invalid(argName, "Unknown collision layer in pair: "+item)
+			continue
+		}
+		engine.SetLayersCollide(a, b, collide)
+	}
+}
+
+func (c *CollisionLayers) Trigger(value float32) {
+	return
+}
+
+//layered is anything that can be put on a collision layer
+type layered interface {
+	SetLayer(layer int)
+}
+
+//setLayerArg puts the body on the layer named by the layer arg, if it's set.
+// The layer is looked up once the scene is loaded, after any CollisionLayers
+// entities have added theirs
+func setLayerArg(node *engine.Node, args EntityArgs, body layered) {
+	if !args.Has("layer") {
+		return
+	}
+	name := args.String("layer")
+	afterLoad(node, func() {
+		layer, ok := engine.CollisionLayerFromName(name)
+		if !ok {
+			args.Invalid("layer", "Collision layer "+name+" not found")
+			return
+		}
+		body.SetLayer(layer)
+	})
+}
@@ -0,0 +1,293 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"excavation/engine/gui"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	subtitleTextSize = .035
+	subtitleLayer    = 10 //above the game hud
+	skipControl      = "SkipCutscene"
+)
+
+//Cutscene plays a timeline of events, with game input suspended until
+// it ends or is skipped.  Events are separated by ; in the following format
+// time,action,target[,value]
+// Actions:
+//	camera	plays the target CameraPath entity
+//	trigger	triggers the target entity with value, 1 if not set
+//	audio	triggers the target Audio entity, it's stopped if the cutscene is skipped
+//	subtitle	shows the target string id from the language file for value seconds
+// Args: events, duration, autoStart, skippable
+type Cutscene struct {
+	node      *engine.Node
+	events    []*cutsceneEvent
+	duration  float64
+	skippable bool
+
+	next     int
+	start    float64
+	playing  bool
+	playID   int
+	started  []Entity //camera paths and audio to stop if skipped
+	subtitle *subtitle
+}
+
+type cutsceneEvent struct {
+	time   float64
+	action string
+	target string
+	value  float64
+}
+
+//subtitle is the hud showing the cutscene's current line
+type subtitle struct {
+	hud   *engine.Gui
+	label *gui.Label
+	end   float64
+}
+
+var activeCutscene *Cutscene
+
+func (c *Cutscene) Add(node *engine.Node, args EntityArgs) {
+	c.node = node
+	c.duration = float64(args.Float("duration"))
+	c.skippable = args.Bool("skippable")
+
+	eventList := strings.Split(args.String("events"), ";")
+	for i := range eventList {
+		if strings.TrimSpace(eventList[i]) == "" {
+			continue
+		}
+		if event, ok := parseCutsceneEvent(eventList[i], args); ok {
+			c.events = append(c.events, event)
+		}
+	}
+	sort.Sort(byEventTime(c.events))
+	afterLoad(node, c.checkEvents)
+
+	engine.BindInput(skipCutscene, skipControl)
+
+	if args.Bool("autoStart") {
+		c.Trigger(1)
+	}
+}
+
+func parseCutsceneEvent(item string, args EntityArgs) (*cutsceneEvent, bool) {
+	fields := strings.Split(item, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if len(fields) < 3 {
+		args.Invalid("events", "Event "+item+" needs a time, action and target")
+		return nil, false
+	}
+
+	event := &cutsceneEvent{action: strings.ToLower(fields[1]), target: fields[2], value: 1}
+	var err error
+	if event.time, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		args.Invalid("events", "Invalid time for event: "+item)
+		return nil, false
+	}
+	if len(fields) > 3 {
+		if event.value, err = strconv.ParseFloat(fields[3], 64); err != nil {
+			args.Invalid("events", "Invalid value for event: "+item)
+			return nil, false
+		}
+	}
+
+	switch event.action {
+	case "camera", "trigger", "audio", "subtitle":
+	default:
+		args.Invalid("events", "Unknown action "+fields[1]+" in event: "+item)
+		return nil, false
+	}
+	return event, true
+}
+
+//checkEvents reports event targets that don't exist, or aren't the right
+// kind of entity for the action, once the scene is loaded
+func (c *Cutscene) checkEvents() {
+	for _, event := range c.events {
+		if event.action == "subtitle" {
+			continue
+		}
+		target, ok := EntityFromName(event.target)
+		if !ok {
+			addLoadError("events", "Entity Name: "+event.target+" not found")
+			continue
+		}
+		if _, ok := target.(*CameraPath); event.action == "camera" && !ok {
+			addLoadError("events", "Entity "+event.target+" isn't a camera path")
+		}
+	}
+}
+
+type byEventTime []*cutsceneEvent
+
+func (e byEventTime) Len() int           { return len(e) }
+func (e byEventTime) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byEventTime) Less(i, j int) bool { return e[i].time < e[j].time }
+
+//Trigger plays the cutscene from the start, or skips it if value is 0
+func (c *Cutscene) Trigger(value float32) {
+	if value <= 0 {
+		c.Skip()
+		return
+	}
+	c.Play()
+}
+
+//Play starts the cutscene from the beginning, ending any other cutscene
+func (c *Cutscene) Play() {
+	if activeCutscene != nil && activeCutscene != c {
+		activeCutscene.Skip()
+	}
+	c.stopStarted()
+
+	activeCutscene = c
+	c.playID++
+	playID := c.playID
+	c.playing = true
+	c.next = 0
+	c.start = engine.GameTime()
+	//Esc still brings up the menu
+	engine.SuspendGameInput(skipControl, "Key_Esc")
+
+	engine.AddTask(c.node.Name()+"_Cutscene", func(t *engine.Task) {
+		if playID != c.playID {
+			t.Remove()
+			return
+		}
+		if !c.update() {
+			t.Remove()
+		}
+	}, c, 0, 0)
+}
+
+//Skip jumps to the end of the cutscene.  Remaining trigger events are
+// still fired so the scene ends up the same as if it had played out
+func (c *Cutscene) Skip() {
+	if !c.playing {
+		return
+	}
+	c.stopStarted()
+	for ; c.next < len(c.events); c.next++ {
+		if c.events[c.next].action == "trigger" {
+			c.run(c.events[c.next])
+		}
+	}
+	c.end()
+}
+
+func (c *Cutscene) Playing() bool { return c.playing }
+
+//update runs any events that are due, returns false once the cutscene ends
+func (c *Cutscene) update() bool {
+	elapsed := engine.GameTime() - c.start
+	for c.next < len(c.events) && c.events[c.next].time <= elapsed {
+		c.run(c.events[c.next])
+		c.next++
+	}
+
+	if c.subtitle != nil && engine.GameTime() >= c.subtitle.end {
+		c.hideSubtitle()
+	}
+
+	if elapsed >= c.duration && c.next >= len(c.events) {
+		c.end()
+		return false
+	}
+	return true
+}
+
+func (c *Cutscene) run(event *cutsceneEvent) {
+	if event.action == "subtitle" {
+		c.showSubtitle(event.target, event.value)
+		return
+	}
+
+	target, ok := EntityFromName(event.target)
+	if !ok {
+		raiseEntityError(c.node, "events", "Entity Name: "+event.target+" not found")
+		return
+	}
+
+	switch event.action {
+	case "camera":
+		if _, ok := target.(*CameraPath); !ok {
+			raiseEntityError(c.node, "events", "Entity "+event.target+" isn't a camera path")
+			return
+		}
+		c.started = append(c.started, target)
+		target.Trigger(1)
+	case "audio":
+		c.started = append(c.started, target)
+		target.Trigger(float32(event.value))
+	case "trigger":
+		target.Trigger(float32(event.value))
+	}
+}
+
+//stopStarted stops the camera paths and audio started by the cutscene
+func (c *Cutscene) stopStarted() {
+	for i := range c.started {
+		c.started[i].Trigger(0)
+	}
+	c.started = c.started[:0]
+}
+
+func (c *Cutscene) end() {
+	c.playID++
+	c.playing = false
+	c.hideSubtitle()
+	//hand the view back from any held camera paths
+	for i := range c.started {
+		if path, ok := c.started[i].(*CameraPath); ok {
+			path.Stop()
+		}
+	}
+	c.started = c.started[:0]
+	if activeCutscene == c {
+		activeCutscene = nil
+		engine.ResumeGameInput()
+	}
+}
+
+func (c *Cutscene) showSubtitle(id string, duration float64) {
+	if c.subtitle == nil {
+		c.subtitle = &subtitle{hud: engine.NewGui()}
+		c.subtitle.label = gui.MakeLabel("subtitle", "", subtitleTextSize,
+			engine.NewScreenArea(0.1, 0.85, 1.1, .05, engine.ScreenRelativeLeft))
+		c.subtitle.label.Text.SetAlign(engine.AlignCenter, engine.AlignMiddle)
+		c.subtitle.label.Text.SetWrap(true)
+		c.subtitle.hud.AddWidget(c.subtitle.label)
+		engine.AddHud(c.subtitle.hud, subtitleLayer)
+	}
+	c.subtitle.label.SetTextID(id)
+	c.subtitle.end = engine.GameTime() + duration
+}
+
+func (c *Cutscene) hideSubtitle() {
+	if c.subtitle == nil {
+		return
+	}
+	engine.RemoveHud(c.subtitle.hud)
+	c.subtitle = nil
+}
+
+func skipCutscene(input *engine.Input) {
+	if state, ok := input.ButtonState(); ok && state == engine.StateReleased {
+		if activeCutscene != nil && activeCutscene.skippable {
+			activeCutscene.Skip()
+		}
+	}
+}
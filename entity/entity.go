@@ -6,7 +6,6 @@ package entity
 
 import (
 	"encoding/xml"
-	"errors"
 	"excavation/engine"
 	"strconv"
 	"strings"
@@ -21,37 +20,114 @@ type EntityArgs map[string]string
 
 var entities = make(map[string]Entity)
 
+//LoadEntity creates the entity described in the node's attachment and
+// adds it to the node.  If anything is wrong with the entity, its type
+// or its arguments, an EntityErrors listing every problem is returned
 func LoadEntity(node *engine.Node, attachmentData string) error {
+	loading = true
+	loadingNode = node.Name()
+	loadingType = ""
+	loadingErrors = nil
+	defer func() { loading = false }()
 
-	var newEnt Entity
 	reader := strings.NewReader(attachmentData)
 	decoder := xml.NewDecoder(reader)
 
 	element, err := decoder.Token()
 	if err != nil {
-		return err
+		addLoadError("", "Invalid attachment: "+err.Error())
+		return loadingErrors
+	}
+
+	start, ok := element.(xml.StartElement)
+	if !ok {
+		addLoadError("", "Invalid attachment, expected an element")
+		return loadingErrors
 	}
 
-	attr := element.(xml.StartElement).Attr
+	attr := start.Attr
 	args := make(EntityArgs)
+	var newEnt Entity
 
 	for i := range attr {
 		if strings.ToLower(attr[i].Name.Local) == "type" {
+			loadingType = attr[i].Value
 			newEnt, err = NewEntity(attr[i].Value)
 			if err != nil {
-				return err
+				addLoadError("type", "Unknown entity type "+attr[i].Value)
 			}
 		} else {
 			args[attr[i].Name.Local] = attr[i].Value
 		}
 	}
 
+	if loadingType == "" {
+		addLoadError("type", "No entity type set")
+	}
+	if newEnt == nil {
+		return loadingErrors
+	}
+
 	newEnt.Add(node, args)
 
 	entities[node.Name()] = newEnt
 
+	if len(loadingErrors) != 0 {
+		return loadingErrors
+	}
+	return nil
+
+}
+
+//pendingLoad is work an entity put off until the rest of the scene's
+// entities are loaded
+type pendingLoad struct {
+	node    string
+	entType string
+	f       func()
+}
+
+var pendingLoads []*pendingLoad
+
+//afterLoad runs the function from FinishLoading, once the rest of the scene's
+// entities have been loaded, so entities can find each other by name.  Problems
+// it finds are reported against the node
+func afterLoad(node *engine.Node, f func()) {
+	pendingLoads = append(pendingLoads, &pendingLoad{node.Name(), loadingType, f})
+}
+
+//FinishLoading runs the work entities put off until every entity in the
+// scene was loaded.  Like LoadEntity, an EntityErrors listing every
+// problem is returned
+func FinishLoading() error {
+	loading = true
+	defer func() { loading = false }()
+
+	var errs EntityErrors
+	for len(pendingLoads) != 0 {
+		pending := pendingLoads[0]
+		pendingLoads = pendingLoads[1:]
+
+		loadingNode = pending.node
+		loadingType = pending.entType
+		loadingErrors = nil
+		pending.f()
+		errs = append(errs, loadingErrors...)
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
 	return nil
+}
 
+//RemoveAll forgets every loaded entity, called when the scene they were
+// loaded from is cleared
+func RemoveAll() {
+	releasePlayer()
+	entities = make(map[string]Entity)
+	pendingLoads = nil
+	activeCutscene = nil
 }
 
 func EntityFromName(name string) (Entity, bool) {
@@ -59,31 +135,45 @@ func EntityFromName(name string) (Entity, bool) {
 	return entity, ok
 }
 
+//Has returns true if the argument was set, for optional arguments
+func (e EntityArgs) Has(argName string) bool {
+	_, ok := e[argName]
+	return ok
+}
+
+//Invalid reports a problem with the argument that an entity found
+// while loading it
+func (e EntityArgs) Invalid(argName, reason string) {
+	addLoadError(argName, reason)
+}
+
 func (e EntityArgs) Bool(argName string) bool {
 	value, ok := e[argName]
 	if !ok {
-		engine.RaiseError(errors.New("Entity has no argument of name " + argName))
+		addLoadError(argName, "Missing argument")
 		return false
 	}
 
 	switch strings.ToLower(value) {
-	case "true":
-		return true
-	case "1":
+	case "true", "1":
 		return true
+	case "false", "0":
+		return false
 	}
+	addLoadError(argName, "Value "+value+" must be true or false")
 	return false
 }
 
 func (e EntityArgs) Float(argName string) float32 {
 	value, ok := e[argName]
 	if !ok {
-		engine.RaiseError(errors.New("Entity has no argument of name " + argName))
+		addLoadError(argName, "Missing argument")
 		return 0
 	}
 
 	fValue, err := strconv.ParseFloat(value, 32)
 	if err != nil {
+		addLoadError(argName, "Value "+value+" isn't a number")
 		return 0
 	}
 	return float32(fValue)
@@ -92,7 +182,7 @@ func (e EntityArgs) Float(argName string) float32 {
 func (e EntityArgs) String(argName string) string {
 	value, ok := e[argName]
 	if !ok {
-		engine.RaiseError(errors.New("Entity has no argument of name " + argName))
+		addLoadError(argName, "Missing argument")
 		return ""
 	}
 
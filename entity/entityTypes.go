@@ -28,6 +28,24 @@ func NewEntity(typeName string) (Entity, error) {
 		return new(PhysicsScene), nil
 	case "physicsbox":
 		return new(PhysicsBox), nil
+	case "physicsmaterial":
+		return new(PhysicsMaterial), nil
+	case "collisionlayers":
+		return new(CollisionLayers), nil
+	case "hinge":
+		return new(Hinge), nil
+	case "slider":
+		return new(Slider), nil
+	case "rope":
+		return new(Rope), nil
+	case "breakablejoint":
+		return new(BreakableJoint), nil
+	case "camerapath":
+		return new(CameraPath), nil
+	case "cutscene":
+		return new(Cutscene), nil
+	case "triggervolume":
+		return new(TriggerVolume), nil
 
 	}
 	return nil, errors.New("Entity of type " + typeName + " not found.")
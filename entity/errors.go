@@ -0,0 +1,75 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"strings"
+)
+
+//EntityError is a problem with a single entity in a scene
+type EntityError struct {
+	Node      string
+	Type      string
+	Attribute string //empty if the problem isn't with an attribute
+	Reason    string
+}
+
+func (e *EntityError) Error() string {
+	msg := "Entity " + e.Node
+	if e.Type != "" {
+		msg += " (" + e.Type + ")"
+	}
+	if e.Attribute != "" {
+		msg += " attribute " + e.Attribute
+	}
+	return msg + ": " + e.Reason
+}
+
+//EntityErrors is every problem found loading an entity
+type EntityErrors []*EntityError
+
+func (e EntityErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+//the entity currently being loaded, so problems found reading its
+// arguments can be reported with its node name
+var (
+	loading       bool
+	loadingNode   string
+	loadingType   string
+	loadingErrors EntityErrors
+)
+
+//addLoadError records a problem with the entity being loaded.  Problems
+// found outside of LoadEntity are raised instead
+func addLoadError(attribute, reason string) {
+	err := &EntityError{
+		Node:      loadingNode,
+		Type:      loadingType,
+		Attribute: attribute,
+		Reason:    reason,
+	}
+	if !loading {
+		engine.RaiseError(err)
+		return
+	}
+	loadingErrors = append(loadingErrors, err)
+}
+
+//raiseEntityError reports a problem an entity runs into once the scene
+// is running, against the entity's own node
+func raiseEntityError(node *engine.Node, attribute, reason string) {
+	engine.RaiseError(&EntityError{
+		Node:      node.Name(),
+		Attribute: attribute,
+		Reason:    reason,
+	})
+}
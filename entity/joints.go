@@ -0,0 +1,325 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"math"
+	"strings"
+)
+
+const (
+	defaultHingeSpeed  = 90  //degrees per second
+	defaultSliderSpeed = 1   //units per second
+	defaultMotorForce  = 100 //max force or torque the motor uses
+)
+
+//physicsBodyEntity is an entity with a dynamic body that joints can connect
+type physicsBodyEntity interface {
+	PhysicsBody() *engine.PhysicsBody
+}
+
+//bodyFromName returns the body of the named physics entity
+func bodyFromName(argName, name string) (*engine.PhysicsBody, bool) {
+	ent, ok := EntityFromName(name)
+	if !ok {
+		addLoadError(argName, "Entity Name: "+name+" not found")
+		return nil, false
+	}
+	bodyEnt, ok := ent.(physicsBodyEntity)
+	if !ok {
+		addLoadError(argName, "Entity "+name+" doesn't have a physics body")
+		return nil, false
+	}
+	return bodyEnt.PhysicsBody(), true
+}
+
+//jointPivot returns the node's position, and the direction of the axis
+// arg (x, y or z of the node, defaulting to y)
+func jointPivot(node *engine.Node, args EntityArgs) (pivot, pin [3]float32) {
+	matrix := node.AbsoluteTransMat().Array()
+	pivot = [3]float32{matrix[12], matrix[13], matrix[14]}
+
+	column := 4
+	if args.Has("axis") {
+		switch strings.ToLower(args.String("axis")) {
+		case "x":
+			column = 0
+		case "y":
+		case "z":
+			column = 8
+		default:
+			args.Invalid("axis", "Must be x, y or z")
+		}
+	}
+	pin = [3]float32{matrix[column], matrix[column+1], matrix[column+2]}
+	return pivot, pin
+}
+
+//jointBreak is shared by joints that can break, it triggers the targets
+// when they do
+type jointBreak struct {
+	node       *engine.Node
+	breakForce float32
+	targets    []string
+}
+
+//readBreak reads the breakForce and targets args as the joint is added,
+// the targets are checked once the scene is loaded
+func (b *jointBreak) readBreak(node *engine.Node, args EntityArgs) {
+	b.node = node
+	b.breakForce = optionalFloat(args, "breakForce", 0)
+	if args.Has("targets") {
+		b.targets = splitNames(args.String("targets"))
+	}
+	checkTargets(node, "targets", b.targets)
+}
+
+//setup makes the joint breakable once it's created
+func (b *jointBreak) setup(joint *engine.Joint) {
+	joint.BreakForce = b.breakForce
+	joint.OnBreak = b.broke
+}
+
+func (b *jointBreak) broke(joint *engine.Joint) {
+	triggerNames(b.node, "targets", b.targets, 1)
+}
+
+//motorJoint is a hinge or slider.  With limits, triggering it drives the
+// motor to the position that far between them, so 1 opens a door and 0
+// closes it.  Without limits the value is the motor's speed, 0 stops it
+type motorJoint struct {
+	jointBreak
+	jointType int
+	joint     *engine.Joint
+	limited   bool
+	min, max  float32
+	speed     float32
+	force     float32
+	pending   float32 //trigger value received before the joint was created
+	triggered bool
+}
+
+func (m *motorJoint) add(node *engine.Node, args EntityArgs, jointType int) {
+	m.jointType = jointType
+
+	//hinges are set in degrees
+	unit := float32(1)
+	minArg, maxArg := "minDistance", "maxDistance"
+	m.speed = optionalFloat(args, "speed", defaultSliderSpeed)
+	if jointType == engine.JointHinge {
+		unit = math.Pi / 180
+		minArg, maxArg = "minAngle", "maxAngle"
+		m.speed = optionalFloat(args, "speed", defaultHingeSpeed) * unit
+	}
+	m.force = optionalFloat(args, "force", defaultMotorForce)
+	if args.Has(minArg) || args.Has(maxArg) {
+		m.limited = true
+		m.min = optionalFloat(args, minArg, 0) * unit
+		m.max = optionalFloat(args, maxArg, 0) * unit
+		if m.min > m.max {
+			args.Invalid(minArg, "Must not be greater than "+maxArg)
+			m.min, m.max = m.max, m.min
+		}
+	}
+
+	bodyName := args.String("body")
+	parentName := ""
+	if args.Has("parent") {
+		parentName = args.String("parent")
+	}
+	pivot, pin := jointPivot(node, args)
+	m.readBreak(node, args)
+
+	afterLoad(node, func() {
+		child, ok := bodyFromName("body", bodyName)
+		if !ok {
+			return
+		}
+		var parent *engine.PhysicsBody
+		if parentName != "" {
+			if parent, ok = bodyFromName("parent", parentName); !ok {
+				return
+			}
+		}
+
+		if m.jointType == engine.JointHinge {
+			m.joint = engine.AddHinge(child, parent, pivot, pin)
+		} else {
+			m.joint = engine.AddSlider(child, parent, pivot, pin)
+		}
+		if m.limited {
+			m.joint.SetLimits(m.min, m.max)
+		}
+		m.setup(m.joint)
+		if m.triggered {
+			m.Trigger(m.pending)
+		}
+	})
+}
+
+func (m *motorJoint) Trigger(value float32) {
+	if m.joint == nil {
+		m.pending = value
+		m.triggered = true
+		return
+	}
+	if m.limited {
+		m.joint.SetMotorTarget(m.min+(m.max-m.min)*value, m.speed, m.force)
+		return
+	}
+	if value == 0 {
+		m.joint.StopMotor()
+		return
+	}
+	m.joint.SetMotor(m.speed*value, m.force)
+}
+
+//Hinge rotates a physics body around the node's axis, such as a door.
+// Args: body, the physics entity to rotate
+// Optional args: parent (physics entity the hinge is attached to, the world
+// if not set), axis (x, y or z, defaults to y), minAngle, maxAngle,
+// speed (degrees per second), force, breakForce, targets (triggered with 1
+// when the hinge breaks)
+type Hinge struct {
+	motorJoint
+}
+
+func (h *Hinge) Add(node *engine.Node, args EntityArgs) {
+	h.add(node, args, engine.JointHinge)
+}
+
+//Slider moves a physics body along the node's axis, such as a platform.
+// Args and triggering are the same as a Hinge, with minDistance and
+// maxDistance in place of the angles, and speed in units per second
+type Slider struct {
+	motorJoint
+}
+
+func (s *Slider) Add(node *engine.Node, args EntityArgs) {
+	s.add(node, args, engine.JointSlider)
+}
+
+//Rope links physics bodies into a rope or chain with ball joints, hanging
+// from the node's position.
+// Args: bodies, a comma separated list of physics entities from the top
+// of the rope down
+// Optional args: anchor (physics entity the top hangs from, the world if
+// not set), cone and twist (limits of each link in degrees), breakForce,
+// targets (triggered with 1 when a link breaks)
+// Triggering the rope with 0 lets go of the anchor
+type Rope struct {
+	jointBreak
+	links []*engine.Joint
+}
+
+func (r *Rope) Add(node *engine.Node, args EntityArgs) {
+	names := splitNames(args.String("bodies"))
+	if len(names) == 0 {
+		args.Invalid("bodies", "No bodies in the rope")
+		return
+	}
+	anchorName := ""
+	if args.Has("anchor") {
+		anchorName = args.String("anchor")
+	}
+	matrix := node.AbsoluteTransMat().Array()
+	top := [3]float32{matrix[12], matrix[13], matrix[14]}
+	limited := args.Has("cone") || args.Has("twist")
+	cone := optionalFloat(args, "cone", 180) * math.Pi / 180
+	twist := optionalFloat(args, "twist", 180) * math.Pi / 180
+	r.readBreak(node, args)
+
+	afterLoad(node, func() {
+		var parent *engine.PhysicsBody
+		if anchorName != "" {
+			var ok bool
+			if parent, ok = bodyFromName("anchor", anchorName); !ok {
+				return
+			}
+		}
+
+		pivot := top
+		for i := range names {
+			child, ok := bodyFromName("bodies", names[i])
+			if !ok {
+				return
+			}
+			position := bodyPosition(child)
+			if i > 0 {
+				//links join half way between each other
+				last := bodyPosition(parent)
+				pivot = [3]float32{(last[0] + position[0]) / 2, (last[1] + position[1]) / 2,
+					(last[2] + position[2]) / 2}
+			}
+
+			link := engine.AddBallSocket(child, parent, pivot)
+			if limited {
+				pin := [3]float32{position[0] - pivot[0], position[1] - pivot[1], position[2] - pivot[2]}
+				link.SetConeLimits(pin, cone, twist)
+			}
+			r.setup(link)
+			r.links = append(r.links, link)
+			parent = child
+		}
+	})
+}
+
+func bodyPosition(body *engine.PhysicsBody) [3]float32 {
+	matrix := body.Node.AbsoluteTransMat().Array()
+	return [3]float32{matrix[12], matrix[13], matrix[14]}
+}
+
+func (r *Rope) Trigger(value float32) {
+	if value <= 0 && len(r.links) > 0 {
+		r.links[0].Remove()
+	}
+}
+
+//BreakableJoint holds a physics body in place until it's pulled harder
+// than the break force.
+// Args: body, breakForce
+// Optional args: parent (physics entity it's held to, the world if not set),
+// targets (triggered with 1 when it breaks)
+// Triggering it with 0 breaks it
+type BreakableJoint struct {
+	jointBreak
+	joint *engine.Joint
+}
+
+func (b *BreakableJoint) Add(node *engine.Node, args EntityArgs) {
+	bodyName := args.String("body")
+	if args.Float("breakForce") <= 0 {
+		args.Invalid("breakForce", "Must be greater than 0")
+	}
+	parentName := ""
+	if args.Has("parent") {
+		parentName = args.String("parent")
+	}
+	pivot, _ := jointPivot(node, args)
+	b.readBreak(node, args)
+
+	afterLoad(node, func() {
+		child, ok := bodyFromName("body", bodyName)
+		if !ok {
+			return
+		}
+		var parent *engine.PhysicsBody
+		if parentName != "" {
+			if parent, ok = bodyFromName("parent", parentName); !ok {
+				return
+			}
+		}
+		b.joint = engine.AddFixedJoint(child, parent, pivot)
+		b.setup(b.joint)
+	})
+}
+
+func (b *BreakableJoint) Trigger(value float32) {
+	if value <= 0 && b.joint != nil && !b.joint.Broken() {
+		b.joint.Remove()
+		b.broke(b.joint)
+	}
+}
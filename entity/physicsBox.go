@@ -8,6 +8,9 @@ import (
 	"excavation/engine"
 )
 
+//PhysicsBox is a dynamic box body.
+// Args: x, y, z, mass
+// Optional args: see setupBody
 type PhysicsBox struct {
 	body *engine.PhysicsBody
 }
@@ -16,8 +19,11 @@ func (p *PhysicsBox) Add(node *engine.Node, args EntityArgs) {
 	collision := engine.PhysicsWorld().CreateBox(args.Float("x"), args.Float("y"), args.Float("z"),
 		int(node.H3DNode), &[16]float32{})
 	p.body = engine.AddPhysicsBodyFromCollision(node, collision, args.Float("mass"))
+	setupBody(p.body, args)
 }
 
+func (p *PhysicsBox) PhysicsBody() *engine.PhysicsBody { return p.body }
+
 func (p *PhysicsBox) Trigger(value float32) {
 	return
 }
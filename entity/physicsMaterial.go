@@ -0,0 +1,76 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"strconv"
+	"strings"
+)
+
+//PhysicsMaterial defines a material that physics entities can use with
+// their material arg.
+// Optional args, defaulting to engine.DefaultMaterialProperties:
+// name (defaults to the node's name), staticFriction, kineticFriction,
+// elasticity, softness, and pairs, which override how this material
+// behaves against others, separated by ; in the following format
+// material,staticFriction,kineticFriction,elasticity,softness
+type PhysicsMaterial struct {
+	material *engine.PhysicsMaterial
+}
+
+func (p *PhysicsMaterial) Add(node *engine.Node, args EntityArgs) {
+	name := node.Name()
+	if args.Has("name") {
+		name = args.String("name")
+	}
+
+	defaults := engine.DefaultMaterialProperties
+	p.material = engine.DefinePhysicsMaterial(name, engine.MaterialProperties{
+		StaticFriction:  optionalFloat(args, "staticFriction", defaults.StaticFriction),
+		KineticFriction: optionalFloat(args, "kineticFriction", defaults.KineticFriction),
+		Elasticity:      optionalFloat(args, "elasticity", defaults.Elasticity),
+		Softness:        optionalFloat(args, "softness", defaults.Softness),
+	})
+
+	if !args.Has("pairs") {
+		return
+	}
+	for _, item := range strings.Split(args.String("pairs"), ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		fields := strings.Split(item, ",")
+		if len(fields) != 5 {
+			args.Invalid("pairs", "Pair "+item+" needs a material, static friction, kinetic friction,"+
+				" elasticity and softness")
+			continue
+		}
+
+		var values [4]float32
+		valid := true
+		for i := range values {
+			value, err := strconv.ParseFloat(strings.TrimSpace(fields[i+1]), 32)
+			if err != nil {
+				args.Invalid("pairs", "Invalid value for pair: "+item)
+				valid = false
+				break
+			}
+			values[i] = float32(value)
+		}
+		if valid {
+			engine.SetMaterialPair(name, strings.TrimSpace(fields[0]), engine.MaterialProperties{
+				StaticFriction:  values[0],
+				KineticFriction: values[1],
+				Elasticity:      values[2],
+				Softness:        values[3],
+			})
+		}
+	}
+}
+
+func (p *PhysicsMaterial) Trigger(value float32) {
+	return
+}
@@ -8,15 +8,47 @@ import (
 	"excavation/engine"
 )
 
+//PhysicsObject is a dynamic body with a convex hull of the node's geometry.
+// Args: mass
+// Optional args: see setupBody
 type PhysicsObject struct {
 	body *engine.PhysicsBody
 }
 
 func (p *PhysicsObject) Add(node *engine.Node, args EntityArgs) {
 	p.body = engine.AddPhysicsBody(node, args.Float("mass"))
+	setupBody(p.body, args)
 
 }
 
+//setupBody sets the body's optional args: material, layer, gravityScale,
+// linearDamping, angularDamping, kinematic, frozen, autoSleep
+func setupBody(body *engine.PhysicsBody, args EntityArgs) {
+	if args.Has("material") {
+		body.SetMaterial(args.String("material"))
+	}
+	setLayerArg(body.Node, args, body)
+	if args.Has("gravityScale") {
+		body.SetGravityScale(args.Float("gravityScale"))
+	}
+	if args.Has("linearDamping") || args.Has("angularDamping") {
+		linear, angular := body.Damping()
+		body.SetDamping(optionalFloat(args, "linearDamping", linear),
+			optionalFloat(args, "angularDamping", angular))
+	}
+	if args.Has("kinematic") {
+		body.SetKinematic(args.Bool("kinematic"))
+	}
+	if args.Has("frozen") {
+		body.SetFrozen(args.Bool("frozen"))
+	}
+	if args.Has("autoSleep") {
+		body.SetAutoSleep(args.Bool("autoSleep"))
+	}
+}
+
+func (p *PhysicsObject) PhysicsBody() *engine.PhysicsBody { return p.body }
+
 func (p *PhysicsObject) Trigger(value float32) {
 	return
 }
@@ -8,12 +8,18 @@ import (
 	"excavation/engine"
 )
 
+//PhysicsScene is static collision built from the node's geometry.
+// Optional args: material, layer
 type PhysicsScene struct {
 	body *engine.PhysicsScene
 }
 
 func (p *PhysicsScene) Add(node *engine.Node, args EntityArgs) {
 	p.body = engine.AddPhysicsScene(node)
+	if args.Has("material") {
+		p.body.SetMaterial(args.String("material"))
+	}
+	setLayerArg(node, args, p.body)
 }
 
 func (p *PhysicsScene) Trigger(value float32) {
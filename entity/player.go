@@ -5,123 +5,234 @@
 package entity
 
 import (
-	"bitbucket.org/tshannon/vmath"
 	"excavation/engine"
 	"math"
+	"strings"
 )
 
 const (
-	maxSpeed        = 20
-	acceleration    = 100
 	mouseMultiplier = 0.001 // makes for some saner numbers in the config file
+	cameraFadeTime  = 0.25  //seconds to fade out and back in when switching to the player camera
 )
 
-var input [3]int
-var vX, vY int
+//Player movement modes, passed in as the Trigger value
+const (
+	PlayerWalk = 1
+	PlayerShip = 2
+)
 
-type Player struct {
-	node              *engine.Node
-	translate, rotate *vmath.Vector3
+//the player receiving input, only one player is possessed at a time
+var possessed *Player
 
-	//Temp movement variables
-	rotationMatrix *vmath.Matrix3
-	curTranslate   *vmath.Vector3
-	relM3          *vmath.Matrix3
+//playerMode is a way of moving the player.  Only one mode is active at a
+// time, and it owns its input bindings and task while it is
+type playerMode interface {
+	enter(p *Player)
+	exit(p *Player)
+}
+
+//Player holds the data shared between its movement modes, and switches
+// between walking and flying a ship.  Only the possessed player has the
+// camera and receives input, triggering another player possesses it.
+// Optional args: mode (walk or ship), active (possessed when loaded,
+// defaults to true for the first player in the scene), plus the args of
+// each mode, see walkMode and shipMode
+type Player struct {
+	node *engine.Node
+
+	//input, only updated while the player is possessed.  The movement axes
+	// are worked out from which controls are held, so a release without a
+	// press, such as one bound before a mode change, can't unbalance them
+	input        [3]int
+	held         map[string]bool
+	vX, vY       int
+	curVx, curVy int
 
 	//mouse
 	invert           bool
 	mouseSensitivity float32
+	cfgHandlers      []*engine.ConfigSubscription
 
-	//movement
-	lastUpdate   float64
-	speed        *vmath.Vector3
-	curVx, curVy int
+	walk     *walkMode
+	ship     *shipMode
+	modeType int
+	mode     playerMode //nil unless possessed
 }
 
 func (p *Player) Add(node *engine.Node, args EntityArgs) {
 	p.node = node
 
-	//TODO: Only activate camera if set to active in arg
-	engine.SetMainCamera(&engine.Camera{p.node})
-	engine.MainCamera().SetOcclusionCulling(true)
+	p.walk = newWalkMode(p, args)
+	p.ship = newShipMode(p, args)
+
+	p.modeType = PlayerWalk
+	if args.Has("mode") {
+		switch strings.ToLower(args.String("mode")) {
+		case "walk":
+		case "ship":
+			p.modeType = PlayerShip
+		default:
+			args.Invalid("mode", "Must be walk or ship")
+		}
+	}
 
-	p.translate = new(vmath.Vector3)
-	p.rotate = new(vmath.Vector3)
-	p.rotationMatrix = new(vmath.Matrix3)
-	p.curTranslate = new(vmath.Vector3)
-	p.relM3 = new(vmath.Matrix3)
-	p.speed = new(vmath.Vector3)
+	active := possessed == nil
+	if args.Has("active") {
+		active = args.Bool("active")
+	}
+	if active {
+		p.possess()
+	}
+}
 
-	p.invert = engine.Cfg().Bool("InvertMouse")
-	p.mouseSensitivity = engine.Cfg().Float("MouseSensitivity") * mouseMultiplier
+//Trigger possesses the player, switching the camera to it if it isn't
+// already, and sets the movement mode to the value, PlayerWalk or
+// PlayerShip.  A value of 0 releases the player
+func (p *Player) Trigger(value float32) {
+	if value <= 0 {
+		p.release()
+		return
+	}
+	mode := int(value)
+	if possessed == p {
+		p.SetMode(mode)
+		return
+	}
 
-	engine.Cfg().RegisterOnWriteHandler(func(cfg *engine.Config) {
-		p.invert = engine.Cfg().Bool("InvertMouse")
-		p.mouseSensitivity = engine.Cfg().Float("MouseSensitivity") * mouseMultiplier
+	//fade out so the camera cut isn't visible
+	engine.FadeOut(cameraFadeTime, nil, func() {
+		p.modeType = mode
+		p.possess()
+		engine.FadeIn(cameraFadeTime, nil)
 	})
+}
 
+//possess gives the player the camera, listener and input, releasing
+// whichever player had them
+func (p *Player) possess() {
+	if possessed != nil && possessed != p {
+		possessed.release()
+	}
+	possessed = p
+
+	engine.SetMainCamera(&engine.Camera{p.node})
+	engine.MainCamera().SetOcclusionCulling(true)
 	l := engine.AudioListener()
 	l.SetNode(p.node)
 
-	engine.BindInput(handlePlayerInput, "Forward", "Backward", "StrafeRight", "StrafeLeft",
-		"MoveUp", "MoveDown")
-	engine.BindInput(handlePitchYaw, "PitchYaw", "PitchUp", "PitchDown", "YawLeft", "YawRight")
-	engine.AddTask("updatePlayer", updatePlayer, p, 0, 0)
+	p.invert = engine.Cfg().Bool("InvertMouse")
+	p.mouseSensitivity = engine.Cfg().Float("MouseSensitivity") * mouseMultiplier
+	p.cfgHandlers = []*engine.ConfigSubscription{
+		engine.Cfg().RegisterOnChangeHandler("InvertMouse", func(cfg *engine.Config, name string) {
+			p.invert = cfg.Bool(name)
+		}),
+		engine.Cfg().RegisterOnChangeHandler("MouseSensitivity", func(cfg *engine.Config, name string) {
+			p.mouseSensitivity = cfg.Float(name) * mouseMultiplier
+		}),
+	}
 
 	engine.SetMousePos(0, 0)
-
+	p.vX, p.vY = 0, 0
+	p.setMode(p.modeType)
 }
 
-func (p *Player) Trigger(value float32) {
-	//TODO: Fade view as camera changes?
-	if value > 0 {
-		engine.SetMainCamera(&engine.Camera{p.node})
-		l := engine.AudioListener()
-		l.SetNode(p.node)
-		engine.SetMousePos(0, 0)
+//release drops the player's input bindings, tasks and config handlers
+func (p *Player) release() {
+	if p.mode != nil {
+		p.mode.exit(p)
+		p.mode = nil
+	}
+	for i := range p.cfgHandlers {
+		p.cfgHandlers[i].Unsubscribe()
+	}
+	p.cfgHandlers = nil
+	//the bindings are gone, so releases of anything still held won't arrive
+	p.clearHeld()
+	if possessed == p {
+		possessed = nil
 	}
 }
 
-func updatePlayer(t *engine.Task) {
-	p := t.Data.(*Player)
+//Possessed returns true if the player is receiving input
+func (p *Player) Possessed() bool { return possessed == p }
+
+//SetMode switches to the movement mode, PlayerWalk or PlayerShip, dropping
+// the previous mode's bindings and task.  If the player isn't possessed
+// the mode is used once it is
+func (p *Player) SetMode(mode int) {
+	p.modeType = mode
+	if possessed == p {
+		p.setMode(mode)
+	}
+}
 
-	elapsedTime := float32(engine.GameTime() - p.lastUpdate)
-	p.lastUpdate = engine.GameTime()
+func (p *Player) setMode(mode int) {
+	var next playerMode = p.walk
+	if mode == PlayerShip {
+		next = p.ship
+	}
+	if next == p.mode {
+		return
+	}
 
-	for i := 0; i < 3; i++ {
-		if input[i] == 0 {
-			p.speed[i] = deccelerate(p.speed[i], elapsedTime)
-		} else {
-			p.speed[i] = accelerate(p.speed[i], elapsedTime, input[i])
-		}
+	if p.mode != nil {
+		p.mode.exit(p)
 	}
+	//the last mode's controls are unbound, so their releases won't arrive
+	p.clearHeld()
+	p.curVx, p.curVy = p.vX, p.vY
+
+	p.mode = next
+	p.mode.enter(p)
+}
 
-	p.translate.ScalarMul(p.speed, elapsedTime)
+//Mode returns the current movement mode
+func (p *Player) Mode() int {
+	return p.modeType
+}
 
-	if !p.invert {
-		p.rotate[1] = (float32(vY-p.curVy) * p.mouseSensitivity)
-	} else {
-		p.rotate[1] = (float32(vY-p.curVy) * (p.mouseSensitivity * -1))
+//releasePlayer releases the possessed player, called when the scene clears
+func releasePlayer() {
+	if possessed != nil {
+		possessed.release()
 	}
+}
 
-	p.rotate[0] = (float32(vX-p.curVx) * p.mouseSensitivity)
+//look returns the pitch and yaw change in radians since it was last called
+func (p *Player) look() (pitch, yaw float32) {
+	pitch = -float32(p.vX-p.curVx) * p.mouseSensitivity
+	if p.invert {
+		pitch = -pitch
+	}
+	yaw = -float32(p.vY-p.curVy) * p.mouseSensitivity
+	p.curVx = p.vX
+	p.curVy = p.vY
+	return pitch, yaw
+}
 
-	p.localTransform()
+//taskName is unique to the player and mode, so the mode's task can be removed
+func (p *Player) taskName(mode string) string {
+	return p.node.Name() + "_" + mode
+}
 
-	p.curVx = vX
-	p.curVy = vY
+//optionalFloat returns the float arg if it's set, otherwise the default
+func optionalFloat(args EntityArgs, argName string, value float32) float32 {
+	if args.Has(argName) {
+		return args.Float(argName)
+	}
+	return value
 }
 
-func accelerate(speed, time float32, modifier int) float32 {
+func accelerate(speed, time float32, modifier int, acceleration, maxSpeed float32) float32 {
 	speed += float32(modifier) * (acceleration * time)
 
-	if math.Abs(float64(speed)) > maxSpeed {
+	if math.Abs(float64(speed)) > float64(maxSpeed) {
 		speed = (maxSpeed * float32(modifier))
 	}
 	return speed
 }
 
-func deccelerate(speed, time float32) float32 {
+func deccelerate(speed, time, acceleration float32) float32 {
 	var modifier float32
 	if speed == 0 {
 		return 0
@@ -139,67 +250,47 @@ func deccelerate(speed, time float32) float32 {
 	return speed
 }
 
-func (p *Player) localTransform() {
-	matrix := p.node.RelativeTransMat()
-
-	matrix.Translation(p.curTranslate)
-
-	p.rotationMatrix.MakeRotationZYX(p.rotate)
-
-	matrix.Upper3x3(p.relM3)
-
-	p.translate.MulM3(p.translate, p.relM3)
-
-	p.rotationMatrix.Mul(p.relM3, p.rotationMatrix)
-
-	p.translate.Add(p.curTranslate, p.translate)
-
-	matrix.MakeFromM3V3(p.rotationMatrix, p.translate)
-
-	p.node.SetRelativeTransMat(matrix)
-
-	p.translate[0] = 0
-	p.translate[1] = 0
-	p.translate[2] = 0
-	p.rotate[0] = 0
-	p.rotate[1] = 0
-	p.rotate[2] = 0
-
-}
-
-func handlePlayerInput(i *engine.Input) {
-	var modifier int
-
-	if i.State == engine.StatePressed {
-		modifier = 1
-	} else {
-		modifier = -1
+func (p *Player) handleMove(i *engine.Input) {
+	if p.held == nil {
+		p.held = make(map[string]bool)
 	}
+	p.held[i.ControlName()] = i.State == engine.StatePressed
 
-	switch i.ControlName() {
-	case "Forward":
-		input[2] += -1 * modifier
-	case "Backward":
-		input[2] += 1 * modifier
-	case "StrafeLeft":
-		input[0] += -1 * modifier
-	case "StrafeRight":
-		input[0] += 1 * modifier
-	case "MoveUp":
-		input[1] += 1 * modifier
-	case "MoveDown":
-		input[1] += -1 * modifier
+	p.input = [3]int{}
+	for control, held := range p.held {
+		if !held {
+			continue
+		}
+		switch control {
+		case "Forward", "ShipForward":
+			p.input[2] += -1
+		case "Backward", "ShipBackward":
+			p.input[2] += 1
+		case "StrafeLeft", "ShipStrafeLeft":
+			p.input[0] += -1
+		case "StrafeRight", "ShipStrafeRight":
+			p.input[0] += 1
+		case "ShipUp":
+			p.input[1] += 1
+		case "ShipDown":
+			p.input[1] += -1
+		}
 	}
+}
 
+//clearHeld forgets the held movement controls
+func (p *Player) clearHeld() {
+	p.held = nil
+	p.input = [3]int{}
 }
 
-func handlePitchYaw(i *engine.Input) {
+func (p *Player) handleLook(i *engine.Input) {
 	//TODO: handle joy and key input
 	x, y, ok := i.MousePos()
 
 	if ok {
-		vY = x
-		vX = y
+		p.vY = x
+		p.vX = y
 		return
 	}
 
@@ -212,19 +303,15 @@ func handlePitchYaw(i *engine.Input) {
 			modifier = -1
 		}
 
-		switch i.ControlName() {
+		switch strings.TrimPrefix(i.ControlName(), "Ship") {
 		case "PitchDown":
-			vX += -1 * modifier
+			p.vX += -1 * modifier
 		case "PitchUp":
-			vX += 1 * modifier
+			p.vX += 1 * modifier
 		case "YawLeft":
-			vY += -1 * modifier
+			p.vY += -1 * modifier
 		case "YawRight":
-			vY += 1 * modifier
+			p.vY += 1 * modifier
 		}
 	}
 }
-
-//TODO: Split into two different types, one for ship movement, one for human movement,
-// use trigger to switch between them
-// Share one player type which holds player data.
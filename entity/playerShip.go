@@ -0,0 +1,153 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"bitbucket.org/tshannon/vmath"
+	"excavation/engine"
+	"math"
+)
+
+const (
+	defaultShipSpeed       = 20
+	defaultShipThrust      = 15
+	defaultShipDrag        = 0.5
+	defaultShipRollSpeed   = 2
+	defaultShipRollThrust  = 4
+	defaultShipAngularDrag = 3
+)
+
+var shipControls = []string{"ShipForward", "ShipBackward", "ShipStrafeRight", "ShipStrafeLeft",
+	"ShipUp", "ShipDown", "ShipRollLeft", "ShipRollRight",
+	"ShipPitchYaw", "ShipPitchUp", "ShipPitchDown", "ShipYawLeft", "ShipYawRight"}
+
+//shipMode flies the player freely in all six directions.  Thrust builds up
+// velocity that carries on after the thrusters stop, slowed by drag, and
+// rolling speeds up and slows down the same way.
+// Optional args: shipSpeed, shipThrust, shipDrag, shipRollSpeed,
+// shipRollThrust, shipAngularDrag
+type shipMode struct {
+	maxSpeed    float32
+	thrust      float32
+	drag        float32
+	rollSpeed   float32
+	rollThrust  float32
+	angularDrag float32
+
+	lastUpdate float64
+	velocity   vmath.Vector3 //parent space, the same as the node's relative matrix
+	roll       float32       //radians per second
+
+	//Temp movement variables
+	translate, rotate *vmath.Vector3
+	rotationMatrix    *vmath.Matrix3
+	curTranslate      *vmath.Vector3
+	relM3             *vmath.Matrix3
+}
+
+func newShipMode(p *Player, args EntityArgs) *shipMode {
+	return &shipMode{
+		maxSpeed:       optionalFloat(args, "shipSpeed", defaultShipSpeed),
+		thrust:         optionalFloat(args, "shipThrust", defaultShipThrust),
+		drag:           optionalFloat(args, "shipDrag", defaultShipDrag),
+		rollSpeed:      optionalFloat(args, "shipRollSpeed", defaultShipRollSpeed),
+		rollThrust:     optionalFloat(args, "shipRollThrust", defaultShipRollThrust),
+		angularDrag:    optionalFloat(args, "shipAngularDrag", defaultShipAngularDrag),
+		translate:      new(vmath.Vector3),
+		rotate:         new(vmath.Vector3),
+		rotationMatrix: new(vmath.Matrix3),
+		curTranslate:   new(vmath.Vector3),
+		relM3:          new(vmath.Matrix3),
+	}
+}
+
+func (s *shipMode) enter(p *Player) {
+	s.velocity = vmath.Vector3{}
+	s.roll = 0
+	s.lastUpdate = engine.GameTime()
+
+	engine.BindInput(p.handleMove, "ShipForward", "ShipBackward", "ShipStrafeRight",
+		"ShipStrafeLeft", "ShipUp", "ShipDown")
+	engine.BindInput(p.handleMove, "ShipRollLeft", "ShipRollRight")
+	engine.BindInput(p.handleLook, "ShipPitchYaw", "ShipPitchUp", "ShipPitchDown",
+		"ShipYawLeft", "ShipYawRight")
+
+	engine.AddTask(p.taskName("ship"), func(t *engine.Task) {
+		s.update(p)
+	}, p, 0, 0)
+}
+
+func (s *shipMode) exit(p *Player) {
+	engine.UnbindInput(shipControls...)
+	engine.RemoveTask(p.taskName("ship"))
+}
+
+func (s *shipMode) update(p *Player) {
+	elapsedTime := float32(engine.GameTime() - s.lastUpdate)
+	s.lastUpdate = engine.GameTime()
+
+	matrix := p.node.RelativeTransMat()
+	matrix.Upper3x3(s.relM3)
+
+	//thrust in the direction the ship is facing
+	thrust := &vmath.Vector3{float32(p.input[0]), float32(p.input[1]), float32(p.input[2])}
+	thrust.MulM3(thrust, s.relM3)
+	for i := 0; i < 3; i++ {
+		s.velocity[i] += thrust[i] * s.thrust * elapsedTime
+		s.velocity[i] -= s.velocity[i] * s.drag * elapsedTime
+	}
+	speed := float32(math.Sqrt(float64(s.velocity[0]*s.velocity[0] + s.velocity[1]*s.velocity[1] +
+		s.velocity[2]*s.velocity[2])))
+	if speed > s.maxSpeed {
+		s.velocity.ScalarMul(&s.velocity, s.maxSpeed/speed)
+	}
+
+	rollInput := 0
+	if p.held["ShipRollLeft"] {
+		rollInput++
+	}
+	if p.held["ShipRollRight"] {
+		rollInput--
+	}
+	s.roll += float32(rollInput) * s.rollThrust * elapsedTime
+	s.roll -= s.roll * s.angularDrag * elapsedTime
+	if s.roll > s.rollSpeed {
+		s.roll = s.rollSpeed
+	} else if s.roll < -s.rollSpeed {
+		s.roll = -s.rollSpeed
+	}
+
+	pitch, yaw := p.look()
+	s.rotate[0] = pitch
+	s.rotate[1] = yaw
+	s.rotate[2] = s.roll * elapsedTime
+
+	s.localTransform(p, elapsedTime)
+}
+
+//localTransform moves the ship by its velocity and rotates it around its
+// own axes
+func (s *shipMode) localTransform(p *Player, elapsedTime float32) {
+	matrix := p.node.RelativeTransMat()
+
+	matrix.Translation(s.curTranslate)
+
+	s.rotationMatrix.MakeRotationZYX(s.rotate)
+
+	matrix.Upper3x3(s.relM3)
+
+	s.rotationMatrix.Mul(s.relM3, s.rotationMatrix)
+
+	s.translate.ScalarMul(&s.velocity, elapsedTime)
+	s.translate.Add(s.curTranslate, s.translate)
+
+	matrix.MakeFromM3V3(s.rotationMatrix, s.translate)
+
+	p.node.SetRelativeTransMat(matrix)
+
+	s.rotate[0] = 0
+	s.rotate[1] = 0
+	s.rotate[2] = 0
+}
@@ -0,0 +1,153 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"bitbucket.org/tshannon/vmath"
+	"excavation/engine"
+	"math"
+)
+
+const (
+	maxPitch = math.Pi/2 - 0.01
+
+	defaultPlayerSpeed        = 6
+	defaultPlayerAcceleration = 40
+)
+
+var walkControls = []string{"Forward", "Backward", "StrafeRight", "StrafeLeft", "Jump", "Crouch",
+	"PitchYaw", "PitchUp", "PitchDown", "YawLeft", "YawRight"}
+
+//walkMode moves the player on foot with a character controller.
+// Optional args, defaulting to engine.DefaultCharacterSettings:
+// speed, acceleration, radius, height, crouchHeight, eyeHeight,
+// stepHeight, maxSlope, gravity, jumpSpeed
+type walkMode struct {
+	controller     *engine.CharacterController
+	yaw, pitch     float32
+	rotationMatrix *vmath.Matrix3
+	worldMatrix    *vmath.Matrix4
+
+	maxSpeed     float32
+	acceleration float32
+	lastUpdate   float64
+	speed        [3]float32
+	jump         bool
+	crouch       bool
+}
+
+func newWalkMode(p *Player, args EntityArgs) *walkMode {
+	w := &walkMode{rotationMatrix: new(vmath.Matrix3), worldMatrix: new(vmath.Matrix4)}
+	w.maxSpeed = optionalFloat(args, "speed", defaultPlayerSpeed)
+	w.acceleration = optionalFloat(args, "acceleration", defaultPlayerAcceleration)
+
+	settings := engine.DefaultCharacterSettings
+	settings.Radius = optionalFloat(args, "radius", settings.Radius)
+	settings.Height = optionalFloat(args, "height", settings.Height)
+	settings.CrouchHeight = optionalFloat(args, "crouchHeight", settings.CrouchHeight)
+	settings.EyeHeight = optionalFloat(args, "eyeHeight", settings.EyeHeight)
+	settings.StepHeight = optionalFloat(args, "stepHeight", settings.StepHeight)
+	settings.MaxSlope = optionalFloat(args, "maxSlope", settings.MaxSlope)
+	settings.Gravity = optionalFloat(args, "gravity", settings.Gravity)
+	settings.JumpSpeed = optionalFloat(args, "jumpSpeed", settings.JumpSpeed)
+	if settings.CrouchHeight > settings.Height {
+		args.Invalid("crouchHeight", "Must not be taller than the height")
+		settings.CrouchHeight = settings.Height
+	}
+	if settings.Height < settings.Radius*2 {
+		args.Invalid("height", "Must be at least twice the radius")
+		settings.Height = settings.Radius * 2
+	}
+
+	w.controller = engine.NewCharacterController(p.node, settings)
+	//trigger volumes only see the player while it's walking
+	w.controller.SetEnabled(false)
+	return w
+}
+
+func (w *walkMode) enter(p *Player) {
+	//stand where the node is, facing the way it faces
+	matrix := p.node.AbsoluteTransMat().Array()
+	w.controller.SetFeet([3]float32{matrix[12], matrix[13] - w.controller.Settings.EyeHeight,
+		matrix[14]})
+	w.yaw = float32(math.Atan2(float64(matrix[8]), float64(matrix[10])))
+	w.pitch = 0
+	w.speed = [3]float32{}
+	w.jump, w.crouch = false, false
+	w.lastUpdate = engine.GameTime()
+	w.controller.SetEnabled(true)
+
+	engine.BindInput(p.handleMove, "Forward", "Backward", "StrafeRight", "StrafeLeft")
+	engine.BindInput(func(i *engine.Input) {
+		if state, ok := i.ButtonState(); ok {
+			w.jump = state == engine.StatePressed
+		}
+	}, "Jump")
+	engine.BindInput(func(i *engine.Input) {
+		if state, ok := i.ButtonState(); ok {
+			w.crouch = state == engine.StatePressed
+		}
+	}, "Crouch")
+	engine.BindInput(p.handleLook, "PitchYaw", "PitchUp", "PitchDown", "YawLeft", "YawRight")
+
+	engine.AddTask(p.taskName("walk"), func(t *engine.Task) {
+		w.update(p)
+	}, p, 0, 0)
+}
+
+func (w *walkMode) exit(p *Player) {
+	engine.UnbindInput(walkControls...)
+	engine.RemoveTask(p.taskName("walk"))
+	w.controller.SetCrouch(false)
+	w.controller.SetEnabled(false)
+}
+
+func (w *walkMode) update(p *Player) {
+	elapsedTime := float32(engine.GameTime() - w.lastUpdate)
+	w.lastUpdate = engine.GameTime()
+
+	pitch, yaw := p.look()
+	w.pitch += pitch
+	w.yaw += yaw
+	if w.pitch > maxPitch {
+		w.pitch = maxPitch
+	} else if w.pitch < -maxPitch {
+		w.pitch = -maxPitch
+	}
+
+	//walk relative to the way the player is facing
+	for _, i := range [2]int{0, 2} {
+		if p.input[i] == 0 {
+			w.speed[i] = deccelerate(w.speed[i], elapsedTime, w.acceleration)
+		} else {
+			w.speed[i] = accelerate(w.speed[i], elapsedTime, p.input[i], w.acceleration, w.maxSpeed)
+		}
+	}
+	sin, cos := float32(math.Sin(float64(w.yaw))), float32(math.Cos(float64(w.yaw)))
+	walk := [3]float32{
+		w.speed[0]*cos + w.speed[2]*sin,
+		0,
+		-w.speed[0]*sin + w.speed[2]*cos,
+	}
+
+	if w.jump {
+		w.controller.Jump()
+	}
+	w.controller.SetCrouch(w.crouch)
+	w.controller.Move(walk, elapsedTime)
+
+	w.localTransform(p)
+}
+
+//localTransform sets the node's rotation from the pitch and yaw at the
+// character's eye.  Both are in world space, so it's converted into the
+// space of the node's parent
+func (w *walkMode) localTransform(p *Player) {
+	eye := w.controller.EyePosition()
+	w.rotationMatrix.MakeRotationZYX(&vmath.Vector3{w.pitch, w.yaw, 0})
+	w.worldMatrix.MakeFromM3V3(w.rotationMatrix, &vmath.Vector3{eye[0], eye[1], eye[2]})
+
+	p.node.SetAbsoluteTransMat(w.worldMatrix.Array())
+}
@@ -5,7 +5,6 @@
 package entity
 
 import (
-	"errors"
 	"excavation/engine"
 	"strconv"
 	"strings"
@@ -23,23 +22,35 @@ func (t *Timer) Add(node *engine.Node, args EntityArgs) {
 	t.triggers = make(map[float64]Entity)
 	triggerList := strings.Split(args.String("triggers"), ",")
 
-	for i := 0; i < len(triggerList); i += 2 {
+	if len(triggerList)%2 != 0 {
+		args.Invalid("triggers", "Every entity needs a delay")
+	}
+
+	names := make(map[float64]string)
+	for i := 0; i+1 < len(triggerList); i += 2 {
 		f, err := strconv.ParseFloat(triggerList[i+1], 64)
 		if err != nil {
-			engine.RaiseError(errors.New("Invalid delay for timer: " + t.node.Name() +
-				" and trigger: " + triggerList[i]))
+			args.Invalid("triggers", "Invalid delay for trigger: "+triggerList[i])
+			continue
 		}
-		if trigger, ok := EntityFromName(triggerList[i]); ok {
-			t.triggers[f] = trigger
+		names[f] = triggerList[i]
+	}
+	autoStart := args.Has("autoStart") && args.Bool("autoStart")
 
-		} else {
-			engine.RaiseError(errors.New("Entity Name: " + triggerList[i] + " not found for timer."))
+	//the entities may come after the timer in the scene
+	afterLoad(node, func() {
+		for delay, name := range names {
+			if trigger, ok := EntityFromName(name); ok {
+				t.triggers[delay] = trigger
+			} else {
+				args.Invalid("triggers", "Entity Name: "+name+" not found for timer.")
+			}
 		}
-	}
 
-	if args.Bool("autoStart") {
-		t.Trigger(1)
-	}
+		if autoStart {
+			t.Trigger(1)
+		}
+	})
 }
 
 func (t *Timer) Trigger(value float32) {
@@ -0,0 +1,214 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package entity
+
+import (
+	"excavation/engine"
+	"strings"
+)
+
+var identityMatrix = [16]float32{
+	1, 0, 0, 0,
+	0, 1, 0, 0,
+	0, 0, 1, 0,
+	0, 0, 0, 1,
+}
+
+//TriggerVolume triggers its targets with 1 when enough of what it's
+// filtering for are inside it, and with 0 when they leave.
+// Args: targets, a comma separated list of entity names
+// Optional args:
+//	shape	box, sphere or hull (built from the node's geometry), defaults to box
+//	x, y, z	size of the box
+//	radius	radius of the sphere
+//	filter	player, bodies, any, or a comma separated list of entity names,
+//		defaults to player
+//	count	how many need to be inside before the targets are triggered, defaults to 1
+//	cooldown	seconds after triggering before it can trigger again
+//	once	only triggers the targets once, then disables itself
+// Triggering the volume with 0 disables it, anything else enables it
+type TriggerVolume struct {
+	node     *engine.Node
+	volume   *engine.TriggerVolume
+	targets  []string
+	filter   string
+	names    map[string]bool
+	count    int
+	cooldown float64
+	once     bool
+
+	active    bool
+	fired     bool
+	lastFired float64
+}
+
+func (t *TriggerVolume) Add(node *engine.Node, args EntityArgs) {
+	t.node = node
+	t.targets = splitNames(args.String("targets"))
+	if len(t.targets) == 0 {
+		args.Invalid("targets", "No target entities")
+	}
+	checkTargets(node, "targets", t.targets)
+
+	t.count = 1
+	if args.Has("count") {
+		t.count = int(args.Float("count"))
+		if t.count < 1 {
+			args.Invalid("count", "Must be at least 1")
+			t.count = 1
+		}
+	}
+	t.cooldown = float64(optionalFloat(args, "cooldown", 0))
+	if args.Has("once") {
+		t.once = args.Bool("once")
+	}
+
+	t.filter = "player"
+	if args.Has("filter") {
+		t.filter = strings.ToLower(strings.TrimSpace(args.String("filter")))
+		switch t.filter {
+		case "player", "bodies", "any":
+		default:
+			t.names = make(map[string]bool)
+			for _, name := range splitNames(args.String("filter")) {
+				t.names[name] = true
+			}
+		}
+	}
+
+	shape := "box"
+	if args.Has("shape") {
+		shape = strings.ToLower(args.String("shape"))
+	}
+	world := engine.PhysicsWorld()
+	switch shape {
+	case "box":
+		t.volume = engine.AddTriggerVolume(node, world.CreateBox(args.Float("x"), args.Float("y"),
+			args.Float("z"), int(node.H3DNode), &identityMatrix))
+	case "sphere":
+		radius := args.Float("radius")
+		t.volume = engine.AddTriggerVolume(node, world.CreateSphere(radius, radius, radius,
+			int(node.H3DNode), &identityMatrix))
+	case "hull":
+		t.volume = engine.AddTriggerVolumeFromNode(node)
+	default:
+		args.Invalid("shape", "Must be box, sphere or hull")
+		return
+	}
+
+	t.volume.Filter = t.accepts
+	t.volume.OnEnter = t.entered
+	t.volume.OnExit = t.exited
+}
+
+//splitNames splits a comma separated list, dropping empty names
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+//checkTargets reports any of the named entities that don't exist once
+// the scene is loaded
+func checkTargets(node *engine.Node, argName string, names []string) {
+	afterLoad(node, func() {
+		for i := range names {
+			if _, ok := EntityFromName(names[i]); !ok {
+				addLoadError(argName, "Entity Name: "+names[i]+" not found")
+			}
+		}
+	})
+}
+
+//triggerNames triggers each of the named entities with the value
+func triggerNames(node *engine.Node, argName string, names []string, value float32) {
+	for i := range names {
+		target, ok := EntityFromName(names[i])
+		if !ok {
+			raiseEntityError(node, argName, "Entity Name: "+names[i]+" not found")
+			continue
+		}
+		target.Trigger(value)
+	}
+}
+
+//accepts returns true if the body or character passes the filter
+func (t *TriggerVolume) accepts(other interface{}) bool {
+	var node *engine.Node
+	switch other := other.(type) {
+	case *engine.PhysicsBody:
+		if t.filter == "player" {
+			return false
+		}
+		node = other.Node
+	case *engine.CharacterController:
+		if t.filter == "bodies" {
+			return false
+		}
+		node = other.Node
+	default:
+		return false
+	}
+
+	switch t.filter {
+	case "player":
+		ent, ok := EntityFromName(node.Name())
+		if !ok {
+			return false
+		}
+		_, ok = ent.(*Player)
+		return ok
+	case "bodies", "any":
+		return true
+	}
+	return t.names[node.Name()]
+}
+
+func (t *TriggerVolume) entered(other interface{}) {
+	if t.active || t.volume.Count() < t.count {
+		return
+	}
+	if t.once && t.fired {
+		return
+	}
+	if t.fired && engine.GameTime()-t.lastFired < t.cooldown {
+		return
+	}
+
+	t.active = true
+	t.fired = true
+	t.lastFired = engine.GameTime()
+	t.triggerTargets(1)
+}
+
+func (t *TriggerVolume) exited(other interface{}) {
+	if !t.active || t.volume.Count() >= t.count {
+		return
+	}
+	t.active = false
+	t.triggerTargets(0)
+	if t.once {
+		t.volume.SetEnabled(false)
+	}
+}
+
+func (t *TriggerVolume) triggerTargets(value float32) {
+	triggerNames(t.node, "targets", t.targets, value)
+}
+
+func (t *TriggerVolume) Trigger(value float32) {
+	if t.volume == nil {
+		return
+	}
+	enable := value > 0 && !(t.once && t.fired)
+	if !enable {
+		t.active = false
+	}
+	t.volume.SetEnabled(enable)
+}
@@ -0,0 +1,35 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"excavation/engine"
+	"excavation/engine/gui"
+)
+
+const (
+	hudTextSize = .03
+	hudLayer    = 0
+)
+
+var fpsLabel *gui.Label
+
+//loadHud shows the in game hud, it's removed along with the scene
+func loadHud() {
+	hud := engine.NewGui()
+
+	fpsLabel = gui.MakeLabel("fps", "", hudTextSize,
+		engine.NewScreenArea(0.01, 0.01, .3, .04, engine.ScreenRelativeRight))
+	fpsLabel.Text.SetAlign(engine.AlignRight, engine.AlignMiddle)
+	hud.AddWidget(fpsLabel)
+
+	engine.AddHud(hud, hudLayer)
+	engine.AddTask("FPS", showFPS, nil, 0, 0.25)
+}
+
+func showFPS(t *engine.Task) {
+	fpsLabel.SetTextID("hud.fps", engine.Fps())
+	t.Wait(0.25)
+}
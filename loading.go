@@ -0,0 +1,77 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"excavation/engine"
+	"excavation/engine/gui"
+	"excavation/entity"
+)
+
+const loadingTextSize = .04
+
+var (
+	loadingScreen *engine.Gui
+	loadingBar    *gui.ProgressBar
+	loadingLabel  *gui.Label
+	sceneLoader   *engine.ResourceLoader
+)
+
+//showLoadingScreen shows the progress of the scene's resources loading
+// with the option to cancel and go back to the main menu
+func showLoadingScreen() {
+	loadingScreen = engine.NewGui()
+	loadingScreen.UseMouse = true
+	loadingScreen.HaltInput = true
+	loadingScreen.Bind(func(input *engine.Input) {
+		if state, ok := input.ButtonState(); ok && state == engine.StateReleased {
+			cancelLoading()
+		}
+	}, "Key_Esc")
+
+	loadingLabel = gui.MakeLabel("loading", "", loadingTextSize,
+		engine.NewScreenArea(0.1, 0.75, .8, .05, engine.ScreenRelativeLeft))
+	loadingLabel.SetTextID("loading.progress", 0, 0)
+	loadingScreen.AddWidget(loadingLabel)
+
+	loadingBar = gui.MakeProgressBar("progress", 0,
+		engine.NewScreenArea(0.1, 0.8, .8, .02, engine.ScreenRelativeLeft))
+	loadingScreen.AddWidget(loadingBar)
+
+	loadingScreen.AddWidget(makeMenuButton("cancel", "loading.cancel", func(sender string) {
+		cancelLoading()
+	}, engine.NewScreenArea(0.1, 0.85, .15, .05, engine.ScreenRelativeLeft)))
+
+	engine.LoadGui(loadingScreen)
+	engine.FadeIn(sceneFadeTime, nil)
+}
+
+func updateLoadingScreen(loaded, total int) {
+	loadingLabel.SetTextID("loading.progress", loaded, total)
+	loadingBar.SetValue(sceneLoader.Progress())
+}
+
+func hideLoadingScreen() {
+	engine.UnloadGui(engine.FadeTransition(menuFadeTime))
+	loadingScreen = nil
+	sceneLoader = nil
+}
+
+func cancelLoading() {
+	if sceneLoader != nil {
+		sceneLoader.Cancel()
+	}
+	returnToMainMenu()
+}
+
+//returnToMainMenu clears whatever is loaded and shows the main menu
+func returnToMainMenu() {
+	sceneLoader = nil
+	loadingScreen = nil
+	engine.ClearAll()
+	entity.RemoveAll()
+	engine.SetFade(nil, 0)
+	loadMainMenu()
+}
@@ -9,13 +9,13 @@ import (
 	"excavation/entity"
 	"flag"
 	"fmt"
-	"github.com/jteeuwen/glfw"
 	"runtime"
 	"strings"
 )
 
 const (
-	name = "excavation"
+	name          = "excavation"
+	sceneFadeTime = 0.5 //seconds to fade between scenes
 )
 
 //cmd line options
@@ -30,7 +30,7 @@ func init() {
 func main() {
 	flag.Parse()
 
-	engine.SetDefaultConfigHandler(setCfgDefaults)
+	setCfgSchemas()
 	engine.SetErrorHandler(errHandler)
 
 	if err := engine.Init(name); err != nil {
@@ -60,109 +60,176 @@ func loadMenu(input *engine.Input) {
 	}
 }
 
-//ResetEngine Reloads all config from disk and reopens a new glfw window
-// used for after video settings are changed
-func ResetEngine() {
-	engine.ClearAll()
-	engine.StopMainLoop()
-	//FIXME
-	main()
-}
-
 func errHandler(err error) {
 	fmt.Println(err)
 }
 
-func showFPS(t *engine.Task) {
-	engine.Print("FPS: ", engine.Fps())
-	t.Wait(0.25)
-}
-
-var vsync int
-
 func ToggleVSync(input *engine.Input) {
 	if state, ok := input.ButtonState(); ok {
 		if state == engine.StatePressed {
-			if vsync == 0 {
-				vsync = 1
+			cfg := engine.Cfg()
+			if cfg.Int("VSync") == 0 {
+				cfg.SetValue("VSync", 1)
 			} else {
-				vsync = 0
+				cfg.SetValue("VSync", 0)
+			}
+			if err := cfg.Write(); err != nil {
+				engine.RaiseError(err)
+			}
+			if err := engine.ApplyVideoSettings(); err != nil {
+				engine.RaiseError(err)
 			}
-			glfw.SetSwapInterval(vsync)
 		}
 	}
 }
 
-//loadScene loads a scene and all associated resources in the given
-//scenefile and loads the entities and properties
+//loadScene clears the current scene and loads the given scenefile behind
+// a loading screen.  Once all of the scene's resources are loaded, its
+// entities and properties are loaded
 func loadScene(scene string) {
 	if !strings.HasSuffix(scene, ".scene.xml") {
 		scene = scene + ".scene.xml"
 	}
 	//Clear any old scene data and resources
 	engine.ClearAll()
+	entity.RemoveAll()
 	runtime.GC()
-	//TODO:  Loading screen, and camera management
 
+	showLoadingScreen()
+
+	sceneErr := &SceneError{Scene: scene}
 	sceneRes, err := engine.NewScene(scene)
 	if err != nil {
-		panic(err)
+		sceneErr.Err = err
+		sceneLoadFailed(sceneErr)
+		return
 	}
 
 	err = sceneRes.Load()
 	if err != nil {
-		//TODO: Load Main Menu instead
-		panic("Scene file " + scene + " doesn't exist")
+		sceneErr.Missing = append(sceneErr.Missing, scene)
+		sceneLoadFailed(sceneErr)
+		return
 	}
 
-	err = engine.LoadAllResources()
-	sceneNode, err := engine.Root.AddScene(sceneRes)
+	sceneLoader = engine.LoadResources(updateLoadingScreen, func(errs []error) {
+		sceneErr.addResourceErrors(errs)
+		finishScene(sceneRes, sceneErr)
+	})
+}
 
+//finishScene adds the loaded scene to the scene graph and loads its entities.
+// Entities are loaded even if resources are missing so that every problem
+// with the scene is reported at once
+func finishScene(sceneRes *engine.Scene, sceneErr *SceneError) {
+	sceneNode, err := engine.Root.AddScene(sceneRes)
 	if err != nil {
-		panic(err)
+		if sceneErr.Err == nil {
+			sceneErr.Err = err
+		}
+		sceneLoadFailed(sceneErr)
+		return
 	}
 
+	//named cameras in the scene can be switched to by entities
+	engine.AddSceneCameras(sceneNode, 0)
+
 	children := sceneNode.Children()
 	for c := range children {
 		//load entities
 		//TODO: Recurse Tree? Only first level entities get loaded now
 		if children[c].Attachment() != "" {
-			err = entity.LoadEntity(children[c], children[c].Attachment())
-		}
-		if err != nil {
-			panic(err)
+			if err := entity.LoadEntity(children[c], children[c].Attachment()); err != nil {
+				sceneErr.addEntityError(err)
+			}
 		}
+	}
+	//entities that refer to others by name are resolved once they're all loaded
+	if err := entity.FinishLoading(); err != nil {
+		sceneErr.addEntityError(err)
+	}
 
+	if sceneErr.HasErrors() {
+		sceneLoadFailed(sceneErr)
+		return
 	}
-	//TODO: gui
-	engine.AddTask("FPS", showFPS, nil, 0, 0.25)
+
+	hideLoadingScreen()
+	loadHud()
+	engine.Resume()
+	engine.FadeIn(sceneFadeTime, nil)
 }
 
-func setCfgDefaults(cfg *engine.Config) {
-	switch cfg.Name {
-	case "excavation.cfg":
-		cfg.SetValue("WindowWidth", 1024)
-		cfg.SetValue("WindowHeight", 728)
-		cfg.SetValue("WindowDepth", 24)
-		cfg.SetValue("Fullscreen", false)
-		cfg.SetValue("VSync", 0)
-		cfg.SetValue("InvertMouse", true)
-		cfg.SetValue("MouseSensitivity", 0.3)
-		cfg.SetValue("AudioDevice", "")
-		cfg.SetValue("MaxAudioSources", 16)
-		cfg.SetValue("MaxAudioBufferSize", 5242880)
-	case "controls.cfg":
-		cfg.SetValue("Forward", "Key_W")
-		cfg.SetValue("Backward", "Key_S")
-		cfg.SetValue("StrafeLeft", "Key_A")
-		cfg.SetValue("StrafeRight", "Key_D")
-		cfg.SetValue("MoveUp", "Key_E")
-		cfg.SetValue("MoveDown", "Key_Space")
-		cfg.SetValue("PitchYaw", "Mouse_Axis0")
-		cfg.SetValue("PitchUp", "Key_Up")
-		cfg.SetValue("PitchDown", "Key_Down")
-		cfg.SetValue("YawLeft", "Key_Left")
-		cfg.SetValue("YawRight", "Key_Right")
-	}
+//sceneLoadFailed reports the errors, goes back to the main menu and shows
+// the report there
+func sceneLoadFailed(sceneErr *SceneError) {
+	engine.RaiseError(sceneErr)
+	returnToMainMenu()
+	showErrorDialog(engine.Localize("error.sceneTitle", sceneErr.Scene), sceneErr.Lines())
+}
 
+func setCfgSchemas() {
+	engine.SetConfigSchema("excavation.cfg", []*engine.ConfigSetting{
+		{Key: "WindowWidth", Type: engine.ConfigInt, Default: 1024, Min: 320, Max: 16384,
+			Description: "Width of the window or fullscreen resolution"},
+		{Key: "WindowHeight", Type: engine.ConfigInt, Default: 728, Min: 240, Max: 16384,
+			Description: "Height of the window or fullscreen resolution"},
+		{Key: "WindowDepth", Type: engine.ConfigInt, Default: 24, Min: 16, Max: 32,
+			Description: "Depth buffer bits"},
+		{Key: "Fullscreen", Type: engine.ConfigBool, Default: false,
+			Description: "Run in fullscreen mode"},
+		{Key: "VSync", Type: engine.ConfigInt, Default: 0, Min: 0, Max: 1,
+			Description: "Wait for vertical sync before swapping buffers"},
+		{Key: "FOV", Type: engine.ConfigFloat, Default: 45, Min: 30, Max: 120,
+			Description: "Camera field of view in degrees"},
+		{Key: "Language", Type: engine.ConfigString, Default: engine.DefaultLanguage,
+			Description: "Language of the game's text, matching a file in the lang folder"},
+		{Key: "InvertMouse", Type: engine.ConfigBool, Default: true,
+			Description: "Invert the mouse Y axis"},
+		{Key: "MouseSensitivity", Type: engine.ConfigFloat, Default: 0.3, Min: 0.01, Max: 10,
+			Description: "Mouse look sensitivity"},
+		{Key: "AudioDevice", Type: engine.ConfigString, Default: "",
+			Description: "OpenAL device name, blank for the default device", RequiresRestart: true},
+		{Key: "MaxAudioSources", Type: engine.ConfigInt, Default: 16, Min: 1, Max: 256,
+			Description: "Maximum number of sounds playing at once", RequiresRestart: true},
+		{Key: "MaxAudioBufferSize", Type: engine.ConfigInt, Default: 5242880, Min: 65536, Max: 268435456,
+			Description: "Maximum size in bytes of a single audio buffer", RequiresRestart: true},
+	})
+
+	engine.SetConfigSchema("controls.cfg", []*engine.ConfigSetting{
+		{Key: "Forward", Type: engine.ConfigString, Default: "Key_W"},
+		{Key: "Backward", Type: engine.ConfigString, Default: "Key_S"},
+		{Key: "StrafeLeft", Type: engine.ConfigString, Default: "Key_A"},
+		{Key: "StrafeRight", Type: engine.ConfigString, Default: "Key_D"},
+		{Key: "Jump", Type: engine.ConfigString, Default: "Key_Space"},
+		{Key: "Crouch", Type: engine.ConfigString, Default: "Key_Lctrl"},
+		{Key: "PitchYaw", Type: engine.ConfigString, Default: "Mouse_Axis0"},
+		{Key: "PitchUp", Type: engine.ConfigString, Default: "Key_Up"},
+		{Key: "PitchDown", Type: engine.ConfigString, Default: "Key_Down"},
+		{Key: "YawLeft", Type: engine.ConfigString, Default: "Key_Left"},
+		{Key: "YawRight", Type: engine.ConfigString, Default: "Key_Right"},
+		{Key: "ShipForward", Type: engine.ConfigString, Default: "Key_W"},
+		{Key: "ShipBackward", Type: engine.ConfigString, Default: "Key_S"},
+		{Key: "ShipStrafeLeft", Type: engine.ConfigString, Default: "Key_A"},
+		{Key: "ShipStrafeRight", Type: engine.ConfigString, Default: "Key_D"},
+		{Key: "ShipUp", Type: engine.ConfigString, Default: "Key_Space"},
+		{Key: "ShipDown", Type: engine.ConfigString, Default: "Key_Lctrl"},
+		{Key: "ShipRollLeft", Type: engine.ConfigString, Default: "Key_Q"},
+		{Key: "ShipRollRight", Type: engine.ConfigString, Default: "Key_E"},
+		{Key: "ShipPitchYaw", Type: engine.ConfigString, Default: "Mouse_Axis0"},
+		{Key: "ShipPitchUp", Type: engine.ConfigString, Default: "Key_Up"},
+		{Key: "ShipPitchDown", Type: engine.ConfigString, Default: "Key_Down"},
+		{Key: "ShipYawLeft", Type: engine.ConfigString, Default: "Key_Left"},
+		{Key: "ShipYawRight", Type: engine.ConfigString, Default: "Key_Right"},
+		{Key: "SkipCutscene", Type: engine.ConfigString, Default: "Key_Enter",
+			Description: "Skips the cutscene that's playing"},
+		{Key: "MenuAccept", Type: engine.ConfigString, Default: "Joy0_0",
+			Description: "Gamepad button for selecting the focused menu item"},
+		{Key: "MenuBack", Type: engine.ConfigString, Default: "Joy0_1",
+			Description: "Gamepad button for closing menus"},
+		{Key: "MenuUpDown", Type: engine.ConfigString, Default: "Joy0_Axis1",
+			Description: "Gamepad axis for moving up and down through menus"},
+		{Key: "MenuLeftRight", Type: engine.ConfigString, Default: "Joy0_Axis0",
+			Description: "Gamepad axis for moving left and right through menus"},
+	})
 }
@@ -0,0 +1,347 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"excavation/engine"
+	"excavation/engine/gui"
+	"math"
+	"strconv"
+)
+
+const (
+	optionsTextSize    = .04
+	optionsRowHeight   = .07
+	optionsTop         = .2
+	optionsLabelWidth  = .3
+	optionsWidgetX     = .45
+	optionsWidgetWidth = .3
+	confirmTimeout     = 15 //seconds to keep new video settings before reverting
+	fovStep            = 5
+	sensitivityStep    = 0.01
+	menuSlideTime      = 0.3
+)
+
+//videoOptions are the pending settings on the options screen
+type videoOptions struct {
+	modes       []*engine.DisplayMode
+	mode        int
+	fullscreen  bool
+	vsync       bool
+	fov         float32
+	sensitivity float32
+	invert      bool
+}
+
+//savedSettings are the settings in effect before the latest
+// changes were applied, so they can be reverted
+type savedSettings map[string]interface{}
+
+var optionKeys = []string{"WindowWidth", "WindowHeight", "Fullscreen", "VSync", "FOV",
+	"MouseSensitivity", "InvertMouse"}
+
+var (
+	optionsMenu       *engine.Gui
+	options           *videoOptions
+	optionLabels      map[string]*gui.Label
+	resolutionList    *gui.Dropdown
+	fullscreenBox     *gui.Checkbox
+	vsyncBox          *gui.Checkbox
+	invertBox         *gui.Checkbox
+	fovSlider         *gui.Slider
+	sensitivitySlider *gui.Slider
+	confirmMenu       *engine.Gui
+	confirmLabel      *gui.Button
+	confirmTimer      *engine.Tween
+	confirmRemaining  int
+	revertSettings    savedSettings
+)
+
+func loadOptionsMenu() {
+	readOptions()
+
+	optionsMenu = engine.NewGui()
+	optionsMenu.UseMouse = true
+	optionsMenu.HaltInput = true
+	optionsMenu.Bind(closeOptions, "Key_Esc")
+
+	optionLabels = make(map[string]*gui.Label)
+
+	fullscreenBox = addOptionCheckbox(1, "fullscreen", options.fullscreen)
+	vsyncBox = addOptionCheckbox(2, "vsync", options.vsync)
+	fovSlider = addOptionSlider(3, "fov", "FOV", options.fov, fovStep)
+	sensitivitySlider = addOptionSlider(4, "sensitivity", "MouseSensitivity", options.sensitivity,
+		sensitivityStep)
+	invertBox = addOptionCheckbox(5, "invert", options.invert)
+
+	//added last so its open list is drawn over the rows below it
+	addOptionLabel(0, "resolution")
+	modeNames := make([]string, len(options.modes))
+	for i := range options.modes {
+		modeNames[i] = strconv.Itoa(options.modes[i].Width) + " x " + strconv.Itoa(options.modes[i].Height)
+	}
+	resolutionList = gui.MakeDropdown("resolution", modeNames, optionsTextSize, optionWidgetArea(0))
+	resolutionList.Select(options.mode)
+	resolutionList.SelectEvent = func(sender string, index int) {
+		options.mode = index
+	}
+	optionsMenu.AddWidget(resolutionList)
+
+	optionsMenu.AddWidget(makeMenuButton("apply", "options.apply", optionsButtons,
+		engine.NewScreenArea(0.1, optionsTop+optionsRowHeight*7, .12, .05, engine.ScreenRelativeLeft)))
+	optionsMenu.AddWidget(makeMenuButton("back", "options.back", optionsButtons,
+		engine.NewScreenArea(0.25, optionsTop+optionsRowHeight*7, .12, .05, engine.ScreenRelativeLeft)))
+
+	refreshOptionLabels()
+	engine.LoadGui(optionsMenu, engine.SlideTransition(menuSlideTime, -engine.ScreenRatio(), 0))
+}
+
+//readOptions sets the pending options from the current config
+func readOptions() {
+	cfg := engine.Cfg()
+	options = &videoOptions{
+		modes:       engine.DisplayModes(),
+		mode:        -1,
+		fullscreen:  cfg.Bool("Fullscreen"),
+		vsync:       cfg.Int("VSync") != 0,
+		fov:         cfg.Float("FOV"),
+		sensitivity: cfg.Float("MouseSensitivity"),
+		invert:      cfg.Bool("InvertMouse"),
+	}
+
+	width, height := cfg.Int("WindowWidth"), cfg.Int("WindowHeight")
+	for i := range options.modes {
+		if options.modes[i].Width == width && options.modes[i].Height == height {
+			options.mode = i
+			break
+		}
+	}
+	if options.mode == -1 {
+		//current size isn't a display mode, i.e. a custom window size
+		options.modes = append(options.modes, &engine.DisplayMode{Width: width, Height: height})
+		options.mode = len(options.modes) - 1
+	}
+}
+
+func optionWidgetArea(row int) *engine.ScreenArea {
+	return engine.NewScreenArea(optionsWidgetX, optionsTop+optionsRowHeight*float32(row),
+		optionsWidgetWidth, .05, engine.ScreenRelativeLeft)
+}
+
+//addOptionLabel adds the label naming the option in front of its row
+func addOptionLabel(row int, name string) {
+	label := gui.MakeLabel(name+"_label", "", optionsTextSize,
+		engine.NewScreenArea(0.1, optionsTop+optionsRowHeight*float32(row), optionsLabelWidth, .05,
+			engine.ScreenRelativeLeft))
+	label.SetTextID("options." + name)
+	optionLabels[name] = label
+	optionsMenu.AddWidget(label)
+}
+
+func addOptionCheckbox(row int, name string, checked bool) *gui.Checkbox {
+	addOptionLabel(row, name)
+	checkbox := gui.MakeCheckbox(name, "", optionsTextSize, checked, optionWidgetArea(row))
+	checkbox.ChangeEvent = optionChecked
+	optionsMenu.AddWidget(checkbox)
+	return checkbox
+}
+
+//addOptionSlider adds a slider for the config setting, limited to the
+// range in the setting's schema.  If the setting has no range, the slider
+// goes from 0 to twice the current value
+func addOptionSlider(row int, name, key string, value, step float32) *gui.Slider {
+	addOptionLabel(row, name)
+	min, max := float32(0), value*2
+	if setting, ok := engine.Cfg().Setting(key); !ok {
+		engine.RaiseError(errors.New("Config setting " + key + " for the " + name +
+			" option isn't in the schema"))
+	} else if setting.Max > setting.Min {
+		min, max = float32(setting.Min), float32(setting.Max)
+	}
+	slider := gui.MakeSlider(name, min, max, value, optionWidgetArea(row))
+	slider.Step = step
+	slider.ChangeEvent = optionSlid
+	optionsMenu.AddWidget(slider)
+	return slider
+}
+
+//makeMenuButton returns a button showing the localized string for textID
+func makeMenuButton(name, textID string, event func(string), area *engine.ScreenArea) *gui.Button {
+	btn := gui.MakeButton(name, "", optionsTextSize, area)
+	if textID != "" {
+		btn.SetTextID(textID)
+	}
+	btn.ShowBackground(false)
+
+	btn.Text.SetColor(engine.NewColor(75, 75, 75, 255))
+	btn.TextHover.SetColor(engine.NewColor(100, 100, 100, 255))
+	btn.TextClick.SetColor(engine.NewColor(255, 255, 255, 255))
+
+	btn.ClickEvent = event
+	return btn
+}
+
+//refreshOptionLabels shows the slider values in their labels
+func refreshOptionLabels() {
+	optionLabels["fov"].SetTextID("options.fov", options.fov)
+	optionLabels["sensitivity"].SetTextID("options.sensitivity", options.sensitivity)
+}
+
+//refreshOptionWidgets sets the widgets to the pending options
+func refreshOptionWidgets() {
+	resolutionList.Select(options.mode)
+	fullscreenBox.SetChecked(options.fullscreen)
+	vsyncBox.SetChecked(options.vsync)
+	invertBox.SetChecked(options.invert)
+	fovSlider.SetValue(options.fov)
+	sensitivitySlider.SetValue(options.sensitivity)
+	refreshOptionLabels()
+}
+
+func optionChecked(sender string, checked bool) {
+	switch sender {
+	case "fullscreen":
+		options.fullscreen = checked
+	case "vsync":
+		options.vsync = checked
+	case "invert":
+		options.invert = checked
+	}
+}
+
+func optionSlid(sender string, value float32) {
+	switch sender {
+	case "fov":
+		options.fov = value
+	case "sensitivity":
+		options.sensitivity = value
+	}
+	refreshOptionLabels()
+}
+
+func optionsButtons(sender string) {
+	switch sender {
+	case "apply":
+		previewOptions()
+	case "back":
+		closeOptionsMenu()
+	}
+}
+
+func closeOptions(input *engine.Input) {
+	if state, ok := input.ButtonState(); ok && state == engine.StateReleased {
+		closeOptionsMenu()
+	}
+}
+
+func closeOptionsMenu() {
+	engine.UnloadGui(engine.SlideTransition(menuSlideTime, -engine.ScreenRatio(), 0))
+}
+
+//previewOptions applies the pending options and asks the player to keep
+// them.  If they don't answer before the timeout, the old settings are restored
+func previewOptions() {
+	cfg := engine.Cfg()
+	revertSettings = make(savedSettings)
+	for _, key := range optionKeys {
+		revertSettings[key] = cfg.Value(key)
+	}
+
+	mode := options.modes[options.mode]
+	cfg.SetValue("WindowWidth", mode.Width)
+	cfg.SetValue("WindowHeight", mode.Height)
+	cfg.SetValue("Fullscreen", options.fullscreen)
+	if options.vsync {
+		cfg.SetValue("VSync", 1)
+	} else {
+		cfg.SetValue("VSync", 0)
+	}
+	cfg.SetValue("FOV", options.fov)
+	cfg.SetValue("MouseSensitivity", options.sensitivity)
+	cfg.SetValue("InvertMouse", options.invert)
+
+	if err := applyOptions(); err != nil {
+		engine.RaiseError(err)
+		restoreSettings()
+		return
+	}
+
+	confirmMenu = engine.NewGui()
+	confirmMenu.UseMouse = true
+	confirmMenu.HaltInput = true
+	confirmMenu.Bind(func(input *engine.Input) {
+		if state, ok := input.ButtonState(); ok && state == engine.StateReleased {
+			revertOptions()
+		}
+	}, "Key_Esc")
+
+	confirmLabel = makeMenuButton("countdown", "", nil,
+		engine.NewScreenArea(0.1, optionsTop, .8, .05, engine.ScreenRelativeLeft))
+	confirmMenu.AddWidget(confirmLabel)
+	confirmMenu.AddWidget(makeMenuButton("keep", "options.keep", confirmButtons,
+		engine.NewScreenArea(0.1, optionsTop+optionsRowHeight, .12, .05, engine.ScreenRelativeLeft)))
+	confirmMenu.AddWidget(makeMenuButton("revert", "options.revert", confirmButtons,
+		engine.NewScreenArea(0.25, optionsTop+optionsRowHeight, .12, .05, engine.ScreenRelativeLeft)))
+	//tweens update before the guis, so the settings are never reverted
+	// while the confirm gui's widgets are updating
+	confirmRemaining = -1
+	confirmTimer = engine.NewTween(confirmTimeout, nil, updateCountdown).Then(revertOptions)
+
+	engine.LoadGui(confirmMenu)
+}
+
+func applyOptions() error {
+	cfg := engine.Cfg()
+	engine.SetCameraFOV(cfg.Float("FOV"))
+	return engine.ApplyVideoSettings()
+}
+
+func confirmButtons(sender string) {
+	switch sender {
+	case "keep":
+		keepOptions()
+	case "revert":
+		revertOptions()
+	}
+}
+
+func keepOptions() {
+	confirmTimer.Stop()
+	engine.UnloadGui()
+	if err := engine.Cfg().Write(); err != nil {
+		engine.RaiseError(err)
+	}
+}
+
+func revertOptions() {
+	confirmTimer.Stop()
+	engine.UnloadGui()
+	restoreSettings()
+}
+
+//restoreSettings puts back the settings from before the last preview
+// and resets the options screen to match
+func restoreSettings() {
+	cfg := engine.Cfg()
+	for key, value := range revertSettings {
+		cfg.SetValue(key, value)
+	}
+	if err := applyOptions(); err != nil {
+		engine.RaiseError(err)
+	}
+
+	readOptions()
+	refreshOptionWidgets()
+}
+
+//updateCountdown shows the seconds left before the settings are reverted
+func updateCountdown(progress float32) {
+	remaining := int(math.Ceil(confirmTimeout * float64(1-progress)))
+	if remaining != confirmRemaining {
+		confirmRemaining = remaining
+		confirmLabel.SetTextID("options.confirm", remaining)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2012 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"excavation/engine"
+	"excavation/engine/gui"
+	"excavation/entity"
+	"strings"
+)
+
+const errorTextSize = .03
+
+//SceneError is every problem found loading a scene, so they can all be
+// fixed at once instead of one per run
+type SceneError struct {
+	Scene    string
+	Missing  []string //resources that couldn't be loaded
+	Entities entity.EntityErrors
+	Err      error //any other error that stopped the scene from loading
+}
+
+func (s *SceneError) Error() string {
+	return "Unable to load scene " + s.Scene + ":\n" + strings.Join(s.Lines(), "\n")
+}
+
+//Lines returns one line per problem with the scene
+func (s *SceneError) Lines() []string {
+	var lines []string
+	if s.Err != nil {
+		lines = append(lines, s.Err.Error())
+	}
+	for i := range s.Missing {
+		lines = append(lines, engine.Localize("error.missing", s.Missing[i]))
+	}
+	for i := range s.Entities {
+		lines = append(lines, s.Entities[i].Error())
+	}
+	return lines
+}
+
+//HasErrors returns true if anything went wrong loading the scene
+func (s *SceneError) HasErrors() bool {
+	return s.Err != nil || len(s.Missing) != 0 || len(s.Entities) != 0
+}
+
+//addResourceErrors records the resources that failed to load
+func (s *SceneError) addResourceErrors(errs []error) {
+	for i := range errs {
+		if resErr, ok := errs[i].(*engine.ResourceError); ok {
+			s.Missing = append(s.Missing, resErr.Name)
+		} else if s.Err == nil {
+			s.Err = errs[i]
+		}
+	}
+}
+
+//addEntityError records the problems with an entity
+func (s *SceneError) addEntityError(err error) {
+	if entErrs, ok := err.(entity.EntityErrors); ok {
+		s.Entities = append(s.Entities, entErrs...)
+	} else if s.Err == nil {
+		s.Err = err
+	}
+}
+
+var errorDialog *engine.Gui
+
+//showErrorDialog shows the lines in a scrollable list over whatever
+// gui is currently loaded
+func showErrorDialog(title string, lines []string) {
+	errorDialog = engine.NewGui()
+	errorDialog.UseMouse = true
+	errorDialog.HaltInput = true
+	errorDialog.Bind(func(input *engine.Input) {
+		if state, ok := input.ButtonState(); ok && state == engine.StateReleased {
+			closeErrorDialog()
+		}
+	}, "Key_Esc")
+
+	errorDialog.AddWidget(gui.MakeLabel("title", title, optionsTextSize,
+		engine.NewScreenArea(0.1, 0.2, 1.2, .05, engine.ScreenRelativeLeft)))
+
+	errorDialog.AddWidget(gui.MakeList("errors", lines, errorTextSize, .04,
+		engine.NewScreenArea(0.1, 0.27, 1.2, .5, engine.ScreenRelativeLeft)))
+
+	errorDialog.AddWidget(makeMenuButton("ok", "error.ok", func(sender string) {
+		closeErrorDialog()
+	}, engine.NewScreenArea(0.1, 0.8, .1, .05, engine.ScreenRelativeLeft)))
+
+	engine.LoadGui(errorDialog, engine.FadeTransition(menuFadeTime))
+}
+
+func closeErrorDialog() {
+	if errorDialog == nil {
+		return
+	}
+	errorDialog = nil
+	engine.UnloadGui(engine.FadeTransition(menuFadeTime))
+}